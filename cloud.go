@@ -0,0 +1,191 @@
+package scylla
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/transport"
+	"gopkg.in/yaml.v3"
+)
+
+// cloudBundle is Scylla Cloud connection bundle.
+type cloudBundle struct {
+	Datacenters    map[string]cloudDatacenter `yaml:"datacenters"`
+	AuthInfos      map[string]cloudAuthInfo   `yaml:"authInfos"`
+	Contexts       map[string]cloudContext    `yaml:"contexts"`
+	CurrentContext string                     `yaml:"currentContext"`
+	Parameters     struct {
+		DefaultConsistency string `yaml:"defaultConsistency"`
+	} `yaml:"parameters"`
+}
+
+type cloudDatacenter struct {
+	CertificateAuthorityData string `yaml:"certificateAuthorityData"`
+	CertificateAuthorityPath string `yaml:"certificateAuthorityPath"`
+	Server                   string `yaml:"server"`
+	TLSServerName            string `yaml:"tlsServerName"`
+	NodeDomain               string `yaml:"nodeDomain"`
+	InsecureSkipTLSVerify    bool   `yaml:"insecureSkipTlsVerify"`
+}
+
+type cloudAuthInfo struct {
+	ClientCertificateData string `yaml:"clientCertificateData"`
+	ClientCertificatePath string `yaml:"clientCertificatePath"`
+	ClientKeyData         string `yaml:"clientKeyData"`
+	ClientKeyPath         string `yaml:"clientKeyPath"`
+	Username              string `yaml:"username"`
+	Password              string `yaml:"password"`
+}
+
+type cloudContext struct {
+	DatacenterName string `yaml:"datacenterName"`
+	AuthInfoName   string `yaml:"authInfoName"`
+}
+
+// NewCloudSessionConfig returns session config connecting to Scylla Cloud cluster described
+// by connection bundle file. Connections to nodes go through the SNI proxy of their datacenter,
+// the current context datacenter is used for the control connection and as local datacenter
+// of token aware policy.
+func NewCloudSessionConfig(bundlePath, keyspace string) (SessionConfig, error) {
+	b, err := ioutil.ReadFile(bundlePath)
+	if err != nil {
+		return SessionConfig{}, fmt.Errorf("read connection bundle: %w", err)
+	}
+	return parseCloudBundle(b, keyspace)
+}
+
+func parseCloudBundle(b []byte, keyspace string) (SessionConfig, error) {
+	var bundle cloudBundle
+	if err := yaml.Unmarshal(b, &bundle); err != nil {
+		return SessionConfig{}, fmt.Errorf("parse connection bundle: %w", err)
+	}
+
+	ctx, ok := bundle.Contexts[bundle.CurrentContext]
+	if !ok {
+		return SessionConfig{}, fmt.Errorf("connection bundle: unknown current context %q", bundle.CurrentContext)
+	}
+	dc, ok := bundle.Datacenters[ctx.DatacenterName]
+	if !ok {
+		return SessionConfig{}, fmt.Errorf("connection bundle: unknown datacenter %q", ctx.DatacenterName)
+	}
+	auth, ok := bundle.AuthInfos[ctx.AuthInfoName]
+	if !ok {
+		return SessionConfig{}, fmt.Errorf("connection bundle: unknown auth info %q", ctx.AuthInfoName)
+	}
+	if dc.Server == "" || dc.NodeDomain == "" {
+		return SessionConfig{}, fmt.Errorf("connection bundle: datacenter %q must have server and node domain", ctx.DatacenterName)
+	}
+
+	tlsConfig, err := cloudTLSConfig(dc, auth)
+	if err != nil {
+		return SessionConfig{}, fmt.Errorf("connection bundle: %w", err)
+	}
+
+	cfg := DefaultSessionConfig(keyspace, dc.Server)
+	cfg.Policy = transport.NewTokenAwarePolicy(ctx.DatacenterName)
+	cfg.TLSConfig = tlsConfig
+	cfg.SNIProxy = &transport.SNIProxyConfig{
+		Addr:        dc.Server,
+		NodeDomain:  dc.NodeDomain,
+		Datacenters: make(map[string]transport.SNIProxyDatacenter, len(bundle.Datacenters)),
+	}
+	for name, v := range bundle.Datacenters {
+		if v.Server == "" || v.NodeDomain == "" {
+			return SessionConfig{}, fmt.Errorf("connection bundle: datacenter %q must have server and node domain", name)
+		}
+		c, err := cloudTLSConfig(v, auth)
+		if err != nil {
+			return SessionConfig{}, fmt.Errorf("connection bundle: datacenter %q: %w", name, err)
+		}
+		cfg.SNIProxy.Datacenters[name] = transport.SNIProxyDatacenter{
+			Addr:       v.Server,
+			NodeDomain: v.NodeDomain,
+			TLSConfig:  c,
+		}
+	}
+	if auth.Username != "" {
+		cfg.Username = auth.Username
+		cfg.Password = auth.Password
+	}
+	if v := bundle.Parameters.DefaultConsistency; v != "" {
+		c, err := parseConsistency(v)
+		if err != nil {
+			return SessionConfig{}, fmt.Errorf("connection bundle: %w", err)
+		}
+		cfg.DefaultConsistency = c
+	}
+
+	return cfg, nil
+}
+
+func cloudTLSConfig(dc cloudDatacenter, auth cloudAuthInfo) (*tls.Config, error) {
+	ca, err := dataOrFile(dc.CertificateAuthorityData, dc.CertificateAuthorityPath)
+	if err != nil {
+		return nil, fmt.Errorf("certificate authority: %w", err)
+	}
+	cert, err := dataOrFile(auth.ClientCertificateData, auth.ClientCertificatePath)
+	if err != nil {
+		return nil, fmt.Errorf("client certificate: %w", err)
+	}
+	key, err := dataOrFile(auth.ClientKeyData, auth.ClientKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("client key: %w", err)
+	}
+
+	cfg := &tls.Config{
+		ServerName:         dc.NodeDomain,
+		InsecureSkipVerify: dc.InsecureSkipTLSVerify, // nolint:gosec // Set explicitly in the bundle.
+	}
+	if dc.TLSServerName != "" {
+		cfg.ServerName = dc.TLSServerName
+	}
+	if ca != nil {
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("certificate authority: no valid certificates")
+		}
+	}
+	if cert != nil || key != nil {
+		c, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{c}
+	}
+	return cfg, nil
+}
+
+// dataOrFile returns base64 decoded data or content of the file if data is empty.
+func dataOrFile(data, path string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if path != "" {
+		return ioutil.ReadFile(path)
+	}
+	return nil, nil
+}
+
+var consistencyNames = map[string]frame.Consistency{
+	"ANY":          frame.ANY,
+	"ONE":          frame.ONE,
+	"TWO":          frame.TWO,
+	"THREE":        frame.THREE,
+	"QUORUM":       frame.QUORUM,
+	"ALL":          frame.ALL,
+	"LOCAL_QUORUM": frame.LOCALQUORUM,
+	"EACH_QUORUM":  frame.EACHQUORUM,
+	"LOCAL_ONE":    frame.LOCALONE,
+}
+
+func parseConsistency(s string) (frame.Consistency, error) {
+	if v, ok := consistencyNames[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("unknown consistency %q", s)
+}
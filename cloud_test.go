@@ -0,0 +1,113 @@
+package scylla
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+func testCertificate(t *testing.T) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestParseCloudBundle(t *testing.T) {
+	t.Parallel()
+
+	cert, key := testCertificate(t)
+	enc := base64.StdEncoding.EncodeToString
+	bundle := fmt.Sprintf(`kind: Config
+apiVersion: v1alpha1
+datacenters:
+  eu-west-1:
+    certificateAuthorityData: %s
+    server: cql.cluster-id.scylla.com:9142
+    nodeDomain: cql.cluster-id.scylla.com
+  us-east-1:
+    certificateAuthorityData: %s
+    server: us.cql.cluster-id.scylla.com:9142
+    nodeDomain: us.cql.cluster-id.scylla.com
+authInfos:
+  default:
+    clientCertificateData: %s
+    clientKeyData: %s
+    username: scylla
+    password: secret
+contexts:
+  default:
+    datacenterName: eu-west-1
+    authInfoName: default
+currentContext: default
+parameters:
+  defaultConsistency: LOCAL_QUORUM
+`, enc(cert), enc(cert), enc(cert), enc(key))
+
+	cfg, err := parseCloudBundle([]byte(bundle), "ks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Hosts) != 1 || cfg.Hosts[0] != "cql.cluster-id.scylla.com:9142" {
+		t.Fatalf("got hosts %v, expected the proxy", cfg.Hosts)
+	}
+	if cfg.SNIProxy == nil || cfg.SNIProxy.Addr != "cql.cluster-id.scylla.com:9142" || cfg.SNIProxy.NodeDomain != "cql.cluster-id.scylla.com" {
+		t.Fatalf("got SNI proxy %+v", cfg.SNIProxy)
+	}
+	if v := cfg.SNIProxy.Datacenters["us-east-1"]; v.Addr != "us.cql.cluster-id.scylla.com:9142" || v.NodeDomain != "us.cql.cluster-id.scylla.com" || v.TLSConfig == nil {
+		t.Fatalf("got us-east-1 proxy %+v", v)
+	}
+	if v := cfg.SNIProxy.Datacenters["eu-west-1"]; v.Addr != cfg.SNIProxy.Addr || v.NodeDomain != cfg.SNIProxy.NodeDomain {
+		t.Fatalf("got eu-west-1 proxy %+v", v)
+	}
+	if cfg.TLSConfig == nil || cfg.TLSConfig.ServerName != "cql.cluster-id.scylla.com" || len(cfg.TLSConfig.Certificates) != 1 || cfg.TLSConfig.RootCAs == nil {
+		t.Fatalf("got TLS config %+v", cfg.TLSConfig)
+	}
+	if cfg.Username != "scylla" || cfg.Password != "secret" {
+		t.Fatalf("got credentials %s %s", cfg.Username, cfg.Password)
+	}
+	if cfg.DefaultConsistency != frame.LOCALQUORUM {
+		t.Fatalf("got consistency %v", cfg.DefaultConsistency)
+	}
+	if cfg.Keyspace != "ks" {
+		t.Fatalf("got keyspace %s", cfg.Keyspace)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseCloudBundle([]byte("currentContext: missing\n"), "ks"); err == nil {
+		t.Fatal("expected error for unknown context")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"sync"
 	"sync/atomic"
@@ -39,7 +40,7 @@ func main() {
 			log.Fatal(err)
 		}
 		initKeyspaceAndTable(initSession, config.keyspace)
-		initSession.Close()
+		initSession.Close(context.Background())
 	}
 
 	cfg.Keyspace = config.keyspace
@@ -2,7 +2,6 @@ package frame
 
 import (
 	"fmt"
-	"log"
 )
 
 // All the read functions call readByte or readInto as they would want to read a single byte or copy a slice of bytes.
@@ -96,7 +95,7 @@ func (b *Buffer) ReadOpCode() OpCode {
 	o := b.readByte()
 	if Debug {
 		if o > OpAuthSuccess {
-			log.Printf("unknown operation code: %v", o)
+			Log().Warn("unknown operation code", "op_code", o)
 		}
 	}
 	return o
@@ -142,7 +141,7 @@ func (b *Buffer) ReadValue() Value {
 	n := b.ReadInt()
 	if Debug {
 		if n < -2 {
-			log.Printf("unknown value length")
+			Log().Warn("unknown value length", "length", n)
 		}
 	}
 
@@ -157,7 +156,7 @@ func (b *Buffer) ReadInet() Inet {
 	n := b.readByte()
 	if Debug {
 		if n != 4 && n != 16 {
-			log.Printf("unknown ip length")
+			Log().Warn("unknown IP length", "length", n)
 		}
 	}
 	return Inet{IP: b.readCopy(int(n)), Port: b.ReadInt()}
@@ -235,19 +234,19 @@ func (b *Buffer) WriteStartupOptions(m StartupOptions) {
 	if Debug {
 		for k, v := range mandatoryOptions {
 			if s, ok := m[k]; !(ok && contains(v, s)) {
-				log.Printf("unknown mandatory Startup option %s: %s", k, s)
+				Log().Warn("unknown mandatory startup option", "option", k, "value", s)
 			}
 			count++
 		}
 		for k, v := range possibleOptions {
 			if s, ok := m[k]; ok && !contains(v, s) {
-				log.Printf("unknown Startup option %s: %s", k, s)
+				Log().Warn("unknown startup option", "option", k, "value", s)
 			} else if ok {
 				count++
 			}
 		}
 		if count != len(m) {
-			log.Printf("unknown Startup option")
+			Log().Warn("unknown startup option")
 		}
 	}
 	b.WriteStringMap(m)
@@ -257,7 +256,7 @@ func (b *Buffer) ReadTopologyChangeType() TopologyChangeType {
 	t := TopologyChangeType(b.ReadString())
 	if Debug {
 		if _, ok := allTopologyChangeTypes[t]; !ok {
-			log.Printf("unknown TopologyChangeType: %s", t)
+			Log().Warn("unknown topology change type", "type", t)
 		}
 	}
 	return t
@@ -267,7 +266,7 @@ func (b *Buffer) ReadStatusChangeType() StatusChangeType {
 	t := StatusChangeType(b.ReadString())
 	if Debug {
 		if _, ok := allStatusChangeTypes[t]; !ok {
-			log.Printf("unknown StatusChangeType: %s", t)
+			Log().Warn("unknown status change type", "type", t)
 		}
 	}
 	return t
@@ -277,7 +276,7 @@ func (b *Buffer) ReadSchemaChangeType() SchemaChangeType {
 	t := SchemaChangeType(b.ReadString())
 	if Debug {
 		if _, ok := allSchemaChangeTypes[t]; !ok {
-			log.Printf("unknown SchemaChangeType: %s", t)
+			Log().Warn("unknown schema change type", "type", t)
 		}
 	}
 	return t
@@ -288,7 +287,7 @@ func (b *Buffer) ReadSchemaChangeTarget() SchemaChangeTarget {
 	v := SchemaChangeTarget(b.ReadString())
 	if Debug {
 		if _, ok := allSchemaChangeTargets[v]; !ok {
-			log.Printf("unknown SchemaChangeTarget: %s", v)
+			Log().Warn("unknown schema change target", "target", v)
 		}
 	}
 	return v
@@ -301,7 +300,7 @@ func (b *Buffer) ReadErrorCode() ErrorCode {
 func (b *Buffer) ReadConsistency() Consistency {
 	v := b.ReadShort()
 	if Debug && v > LOCALONE {
-		log.Printf("unknown consistency: %v", v)
+		Log().Warn("unknown consistency", "consistency", v)
 	}
 	return v
 }
@@ -310,7 +309,7 @@ func (b *Buffer) ReadWriteType() WriteType {
 	w := WriteType(b.ReadString())
 	if Debug {
 		if _, ok := allWriteTypes[w]; !ok {
-			log.Printf("unknown write type: %s", w)
+			Log().Warn("unknown write type", "write_type", w)
 		}
 	}
 	return w
@@ -412,7 +411,7 @@ func (b *Buffer) ReadOption() Option {
 	default:
 		if Debug {
 			if id < ASCIIID || TinyIntID < id {
-				log.Printf("unknown Option ID: %d", id)
+				Log().Warn("unknown option ID", "id", id)
 			}
 		}
 		return Option{
@@ -1,9 +1,5 @@
 package frame
 
-import (
-	"log"
-)
-
 func (b *Buffer) Write(v Bytes) {
 	_, _ = b.buf.Write(v)
 }
@@ -71,7 +67,7 @@ func (b *Buffer) WritePreparedFlags(v PreparedFlags) {
 func (b *Buffer) WriteOpCode(v OpCode) {
 	if Debug {
 		if _, ok := allOpCodes[v]; !ok {
-			log.Printf("unknown operation code: %v", v)
+			Log().Warn("unknown operation code", "op_code", v)
 		}
 	}
 	b.WriteByte(v)
@@ -84,7 +80,7 @@ func (b *Buffer) WriteUUID(v UUID) {
 func (b *Buffer) WriteConsistency(v Consistency) {
 	if Debug {
 		if v > LOCALONE {
-			log.Printf("unknown consistency: %v", v)
+			Log().Warn("unknown consistency", "consistency", v)
 		}
 	}
 	b.WriteShort(v)
@@ -110,7 +106,7 @@ func (b *Buffer) WriteValue(v Value) {
 	b.WriteInt(v.N)
 	if Debug {
 		if v.N < -2 {
-			log.Printf("unsupported value length")
+			Log().Warn("unsupported value length", "length", v.N)
 		}
 	}
 	if v.N > 0 {
@@ -121,7 +117,7 @@ func (b *Buffer) WriteValue(v Value) {
 func (b *Buffer) WriteInet(v Inet) {
 	if Debug {
 		if l := len(v.IP); l != 4 && l != 16 {
-			log.Printf("unknown IP length")
+			Log().Warn("unknown IP length", "length", l)
 		}
 	}
 	b.WriteByte(Byte(len(v.IP)))
@@ -184,7 +180,7 @@ func (b *Buffer) WriteEventTypes(e []EventType) {
 	if Debug {
 		for _, k := range e {
 			if _, ok := allEventTypes[k]; !ok {
-				log.Printf("unknown EventType %s", k)
+				Log().Warn("unknown event type", "type", k)
 			}
 		}
 	}
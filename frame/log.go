@@ -0,0 +1,74 @@
+package frame
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+)
+
+// Logger is a leveled logger, keyvals are alternating keys and values of structured fields.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// logger holds loggerValue, atomic.Value requires all stored values to be of the same type.
+var logger atomic.Value
+
+type loggerValue struct {
+	Logger
+}
+
+// SetLogger sets logger used to report problems with parsing and writing frames, it's process wide.
+// The driver never calls it, applications which want frame problems in their logs should call it
+// before creating sessions.
+func SetLogger(l Logger) {
+	logger.Store(loggerValue{l})
+}
+
+// Log returns logger set with SetLogger or StdLogger if it's not set, it's used only if Debug is set.
+func Log() Logger {
+	if v, ok := logger.Load().(loggerValue); ok {
+		return v.Logger
+	}
+	return StdLogger{}
+}
+
+// StdLogger writes messages with fields in key=value format using the standard log package.
+type StdLogger struct{}
+
+var _ Logger = StdLogger{}
+
+func (StdLogger) Debug(msg string, keyvals ...interface{}) { stdLog("DEBUG", msg, keyvals) }
+func (StdLogger) Info(msg string, keyvals ...interface{})  { stdLog("INFO", msg, keyvals) }
+func (StdLogger) Warn(msg string, keyvals ...interface{})  { stdLog("WARN", msg, keyvals) }
+func (StdLogger) Error(msg string, keyvals ...interface{}) { stdLog("ERROR", msg, keyvals) }
+
+func stdLog(level, msg string, keyvals []interface{}) {
+	var b strings.Builder
+	b.WriteString(level)
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		b.WriteByte(' ')
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&b, "%v=%v", keyvals[i], keyvals[i+1])
+		} else {
+			fmt.Fprintf(&b, "%v=MISSING", keyvals[i])
+		}
+	}
+	log.Print(b.String())
+}
+
+// NopLogger discards all messages.
+type NopLogger struct{}
+
+var _ Logger = NopLogger{}
+
+func (NopLogger) Debug(msg string, keyvals ...interface{}) {}
+func (NopLogger) Info(msg string, keyvals ...interface{})  {}
+func (NopLogger) Warn(msg string, keyvals ...interface{})  {}
+func (NopLogger) Error(msg string, keyvals ...interface{}) {}
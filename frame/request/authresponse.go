@@ -6,15 +6,20 @@ import (
 
 var _ frame.Request = (*AuthResponse)(nil)
 
-// AuthResponse currently only supports login and password authentication,
-// so it stores them instead of token for convenience.
+// AuthResponse sends Token if it's not nil, otherwise for convenience Username and Password
+// are sent as SASL PLAIN token.
 // Spec: https://github.com/apache/cassandra/blob/adcff3f630c0d07d1ba33bf23fcb11a6db1b9af1/doc/native_protocol_v4.spec#L311
 type AuthResponse struct {
+	Token    frame.Bytes
 	Username string
 	Password string
 }
 
 func (a *AuthResponse) WriteTo(b *frame.Buffer) {
+	if a.Token != nil {
+		b.WriteBytes(a.Token)
+		return
+	}
 	b.WriteLongString("\x00" + a.Username + "\x00" + a.Password)
 }
 
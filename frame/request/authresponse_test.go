@@ -12,6 +12,7 @@ func TestAuthResponseWriteTo(t *testing.T) {
 	t.Parallel()
 	testCases := []struct {
 		name     string
+		token    frame.Bytes
 		username string
 		password string
 		expected []byte
@@ -26,12 +27,21 @@ func TestAuthResponseWriteTo(t *testing.T) {
 				0x00, 0x70, 0x61, 0x73, 0x73, 0x77, 0x6f, 0x72, 0x64,
 			},
 		},
+		{
+			name:     "Should encode token",
+			token:    frame.Bytes{0x01, 0x02, 0x03},
+			username: "username",
+			expected: []byte{
+				0x00, 0x00, 0x00, 0x03,
+				0x01, 0x02, 0x03,
+			},
+		},
 	}
 	for i := 0; i < len(testCases); i++ {
 		tc := testCases[i]
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			ar := AuthResponse{Username: tc.username, Password: tc.password}
+			ar := AuthResponse{Token: tc.token, Username: tc.username, Password: tc.password}
 			var out frame.Buffer
 			ar.WriteTo(&out)
 			if diff := cmp.Diff(out.Bytes(), tc.expected); diff != "" {
@@ -1,8 +1,6 @@
 package response
 
 import (
-	"log"
-
 	"github.com/mmatczuk/scylla-go-driver/frame"
 )
 
@@ -84,7 +82,7 @@ func ParseEvent(b *frame.Buffer) frame.Response {
 	case "SCHEMA_CHANGE":
 		return ParseSchemaChange(b)
 	default:
-		log.Printf("event type not supported: %s", s)
+		frame.Log().Warn("event type not supported", "type", s)
 		return nil
 	}
 }
@@ -1,8 +1,6 @@
 package response
 
 import (
-	"log"
-
 	"github.com/mmatczuk/scylla-go-driver/frame"
 )
 
@@ -125,7 +123,7 @@ func ParseResult(b *frame.Buffer) frame.Response {
 	case SchemaChangeKind:
 		return ParseSchemaChangeResult(b)
 	default:
-		log.Printf("result kind not supported: %d", resultKind)
+		frame.Log().Warn("result kind not supported", "kind", resultKind)
 		return nil
 	}
 }
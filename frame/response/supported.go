@@ -1,7 +1,6 @@
 package response
 
 import (
-	"log"
 	"strconv"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
@@ -48,7 +47,7 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if s, ok := s.Options[ScyllaShard]; ok {
 		if shard, err := strconv.ParseUint(s[0], 10, 16); err != nil {
 			if frame.Debug {
-				log.Printf("scylla: failed to parse %s value %v: %s", ScyllaShard, s, err)
+				frame.Log().Warn("failed to parse scylla option", "option", ScyllaShard, "value", s, "error", err)
 			}
 		} else {
 			si.Shard = uint16(shard)
@@ -57,7 +56,7 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if s, ok := s.Options[ScyllaNrShards]; ok {
 		if nrShards, err := strconv.ParseUint(s[0], 10, 16); err != nil {
 			if frame.Debug {
-				log.Printf("scylla: failed to parse %s value %v: %s", ScyllaNrShards, s, err)
+				frame.Log().Warn("failed to parse scylla option", "option", ScyllaNrShards, "value", s, "error", err)
 			}
 		} else {
 			si.NrShards = uint16(nrShards)
@@ -66,7 +65,7 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if s, ok := s.Options[ScyllaShardingIgnoreMSB]; ok {
 		if msbIgnore, err := strconv.ParseUint(s[0], 10, 8); err != nil {
 			if frame.Debug {
-				log.Printf("scylla: failed to parse %s value %v: %s", ScyllaShardingIgnoreMSB, s, err)
+				frame.Log().Warn("failed to parse scylla option", "option", ScyllaShardingIgnoreMSB, "value", s, "error", err)
 			}
 		} else {
 			si.MsbIgnore = uint8(msbIgnore)
@@ -75,7 +74,7 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if s, ok := s.Options[ScyllaShardAwarePort]; ok {
 		if shardAwarePort, err := strconv.ParseUint(s[0], 10, 16); err != nil {
 			if frame.Debug {
-				log.Printf("scylla: failed to parse %s value %v: %s", ScyllaShardAwarePort, s, err)
+				frame.Log().Warn("failed to parse scylla option", "option", ScyllaShardAwarePort, "value", s, "error", err)
 			}
 		} else {
 			si.ShardAwarePort = uint16(shardAwarePort)
@@ -84,7 +83,7 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if s, ok := s.Options[ScyllaShardAwarePortSSL]; ok {
 		if shardAwarePortSSL, err := strconv.ParseUint(s[0], 10, 16); err != nil {
 			if frame.Debug {
-				log.Printf("scylla: failed to parse %s value %v: %s", ScyllaShardAwarePortSSL, s, err)
+				frame.Log().Warn("failed to parse scylla option", "option", ScyllaShardAwarePortSSL, "value", s, "error", err)
 			}
 		} else {
 			si.ShardAwarePortSSL = uint16(shardAwarePortSSL)
@@ -103,8 +102,8 @@ func (s *Supported) ScyllaSupported() *ScyllaSupported {
 	if si.Partitioner != "org.apache.cassandra.dht.Murmur3Partitioner" ||
 		si.ShardingAlgorithm != "biased-token-round-robin" || si.NrShards == 0 || si.MsbIgnore == 0 {
 		if frame.Debug {
-			log.Printf(`scylla: unsupported sharding configuration, partitioner=%s, algorithm=%s, 
-						no_shards=%d, msb_ignore=%d`, si.Partitioner, si.ShardingAlgorithm, si.NrShards, si.MsbIgnore)
+			frame.Log().Warn("unsupported sharding configuration", "partitioner", si.Partitioner,
+				"algorithm", si.ShardingAlgorithm, "nr_shards", si.NrShards, "msb_ignore", si.MsbIgnore)
 		}
 		return &ScyllaSupported{}
 	}
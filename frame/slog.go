@@ -0,0 +1,39 @@
+//go:build go1.21
+
+package frame
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger adapts slog.Logger to Logger.
+type SlogLogger struct {
+	Logger *slog.Logger
+}
+
+var _ Logger = SlogLogger{}
+
+// NewSlogLogger returns Logger writing to l, if l is nil slog.Default is used.
+func NewSlogLogger(l *slog.Logger) SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return SlogLogger{Logger: l}
+}
+
+func (l SlogLogger) Debug(msg string, keyvals ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelDebug, msg, keyvals...)
+}
+
+func (l SlogLogger) Info(msg string, keyvals ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+func (l SlogLogger) Warn(msg string, keyvals ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+func (l SlogLogger) Error(msg string, keyvals ...interface{}) {
+	l.Logger.Log(context.Background(), slog.LevelError, msg, keyvals...)
+}
@@ -8,6 +8,7 @@ require (
 	github.com/pierrec/lz4/v4 v4.1.14
 	go.uber.org/atomic v1.9.0
 	go.uber.org/goleak v1.1.12
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require github.com/pkg/profile v1.6.0
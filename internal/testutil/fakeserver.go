@@ -0,0 +1,285 @@
+// Package testutil provides fake Scylla nodes for unit tests of the driver.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/frame/response"
+)
+
+// Handler returns response op code and body for a request, handler may also push
+// responses to the connection directly and return op code 0 with nil body to send nothing.
+type Handler func(h frame.Header, body []byte) (frame.OpCode, []byte)
+
+// NewConn returns client side of connection served by h, the server stops when the connection is closed.
+func NewConn(h Handler) net.Conn {
+	client, server := net.Pipe()
+	go Serve(server, h)
+	return client
+}
+
+// Serve reads requests from conn and writes responses returned by h until conn is closed.
+func Serve(conn net.Conn, h Handler) {
+	defer conn.Close()
+
+	for {
+		b := make([]byte, frame.HeaderSize)
+		if _, err := io.ReadFull(conn, b); err != nil {
+			return
+		}
+		var buf frame.Buffer
+		buf.Write(b)
+		header := frame.ParseHeader(&buf)
+		body := make([]byte, header.Length)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		op, res := h(header, body)
+		if op == 0 && res == nil {
+			continue
+		}
+		if err := WriteResponse(conn, header.StreamID, op, res); err != nil {
+			return
+		}
+	}
+}
+
+// WriteResponse writes response frame with body to conn.
+func WriteResponse(conn net.Conn, streamID frame.StreamID, op frame.OpCode, body []byte) error {
+	var out frame.Buffer
+	frame.Header{
+		Version:  0x84,
+		StreamID: streamID,
+		OpCode:   op,
+		Length:   frame.Int(len(body)),
+	}.WriteTo(&out)
+	out.Write(body)
+	_, err := conn.Write(out.Bytes())
+	return err
+}
+
+// Supported returns SUPPORTED response body of a node with a single shard.
+func Supported() []byte {
+	var b frame.Buffer
+	b.WriteStringMultiMap(frame.StringMultiMap{
+		response.ScyllaShard:             {"0"},
+		response.ScyllaNrShards:          {"1"},
+		response.ScyllaPartitioner:       {"org.apache.cassandra.dht.Murmur3Partitioner"},
+		response.ScyllaShardingAlgorithm: {"biased-token-round-robin"},
+		response.ScyllaShardingIgnoreMSB: {"12"},
+	})
+	return b.Bytes()
+}
+
+// Node describes a node of a fake cluster.
+type Node struct {
+	HostID frame.UUID
+	Addr   net.IP
+	DC     string
+	Rack   string
+	Tokens []string
+}
+
+// QueryHandler answers QUERY, PREPARE, EXECUTE and BATCH requests sent to the node with addr.
+type QueryHandler func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte)
+
+// Cluster is a fake cluster of single shard nodes. It answers handshake, topology and keyspace
+// queries, other requests are passed to Query, if Query is nil void result is returned.
+type Cluster struct {
+	Nodes []Node
+	// Keyspaces maps keyspace name to its replication options including class.
+	Keyspaces map[string]map[string]string
+	Query     QueryHandler
+}
+
+// Dial connects to the node listening on addr, use it as driver Dialer.
+func (c *Cluster) Dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(func(h frame.Header, body []byte) (frame.OpCode, []byte) {
+		return c.handle(host, h, body)
+	}), nil
+}
+
+func (c *Cluster) handle(host string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+	switch h.OpCode {
+	case frame.OpOptions:
+		return frame.OpSupported, Supported()
+	case frame.OpStartup, frame.OpRegister:
+		return frame.OpReady, nil
+	case frame.OpQuery:
+		switch {
+		case bytes.Contains(body, []byte("FROM system.local")):
+			return frame.OpResult, c.nodeRows(func(n Node) bool { return n.Addr.String() == host })
+		case bytes.Contains(body, []byte("FROM system.peers")):
+			return frame.OpResult, c.nodeRows(func(n Node) bool { return n.Addr.String() != host })
+		case bytes.Contains(body, []byte("FROM system_schema.keyspaces")):
+			return frame.OpResult, c.keyspaceRows(body)
+		}
+	}
+	if c.Query == nil {
+		return frame.OpResult, VoidResult()
+	}
+	return c.Query(host, h, body)
+}
+
+// nodeRows returns rows result with columns of system.local and system.peers queries.
+func (c *Cluster) nodeRows(filter func(n Node) bool) []byte {
+	var b frame.Buffer
+	b.WriteInt(response.RowsKind)
+	b.WriteResultFlags(frame.GlobalTablesSpec)
+	b.WriteInt(5)
+	b.WriteString("system")
+	b.WriteString("local")
+	for _, col := range []struct {
+		name string
+		id   frame.OptionID
+	}{
+		{"host_id", frame.UUIDID},
+		{"data_center", frame.VarcharID},
+		{"rack", frame.VarcharID},
+		{"tokens", frame.SetID},
+		{"rpc_address", frame.InetID},
+	} {
+		b.WriteString(col.name)
+		b.WriteShort(frame.Short(col.id))
+		if col.id == frame.SetID {
+			b.WriteShort(frame.Short(frame.VarcharID))
+		}
+	}
+
+	var nodes []Node
+	for _, n := range c.Nodes {
+		if filter(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	b.WriteInt(frame.Int(len(nodes)))
+	for _, n := range nodes {
+		b.WriteBytes(n.HostID[:])
+		b.WriteBytes([]byte(n.DC))
+		b.WriteBytes([]byte(n.Rack))
+		var tokens frame.Buffer
+		tokens.WriteInt(frame.Int(len(n.Tokens)))
+		for _, t := range n.Tokens {
+			tokens.WriteBytes([]byte(t))
+		}
+		b.WriteBytes(tokens.Bytes())
+		b.WriteBytes(n.Addr.To4())
+	}
+	return b.Bytes()
+}
+
+// keyspaceRows returns rows result of system_schema.keyspaces query, if the query selects
+// a single keyspace only keyspace which name is bound in body is returned.
+func (c *Cluster) keyspaceRows(body []byte) []byte {
+	byName := bytes.Contains(body, []byte("WHERE keyspace_name"))
+	var names []string
+	for name := range c.Keyspaces {
+		if !byName || bytes.Contains(body, []byte(name)) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b frame.Buffer
+	b.WriteInt(response.RowsKind)
+	b.WriteResultFlags(frame.GlobalTablesSpec)
+	b.WriteInt(2)
+	b.WriteString("system_schema")
+	b.WriteString("keyspaces")
+	b.WriteString("keyspace_name")
+	b.WriteShort(frame.Short(frame.VarcharID))
+	b.WriteString("replication")
+	b.WriteShort(frame.Short(frame.MapID))
+	b.WriteShort(frame.Short(frame.VarcharID))
+	b.WriteShort(frame.Short(frame.VarcharID))
+
+	b.WriteInt(frame.Int(len(names)))
+	for _, name := range names {
+		b.WriteBytes([]byte(name))
+		var m frame.Buffer
+		m.WriteInt(frame.Int(len(c.Keyspaces[name])))
+		for k, v := range c.Keyspaces[name] {
+			m.WriteBytes([]byte(k))
+			m.WriteBytes([]byte(v))
+		}
+		b.WriteBytes(m.Bytes())
+	}
+	return b.Bytes()
+}
+
+// RowsResult returns RESULT response body with rows of a single varchar column,
+// if pagingState is not nil the result has more pages.
+func RowsResult(pagingState []byte, rows ...string) []byte {
+	var b frame.Buffer
+	b.WriteInt(response.RowsKind)
+	flags := frame.GlobalTablesSpec
+	if pagingState != nil {
+		flags |= frame.HasMorePages
+	}
+	b.WriteResultFlags(flags)
+	b.WriteInt(1)
+	if pagingState != nil {
+		b.WriteBytes(pagingState)
+	}
+	b.WriteString("ks")
+	b.WriteString("t")
+	b.WriteString("v")
+	b.WriteShort(frame.Short(frame.VarcharID))
+
+	b.WriteInt(frame.Int(len(rows)))
+	for _, r := range rows {
+		b.WriteBytes([]byte(r))
+	}
+	return b.Bytes()
+}
+
+// PreparedResult returns RESULT response body of a prepared statement with id, which binds
+// columns blob values, partition key consists of columns with pkIndexes.
+func PreparedResult(id []byte, columns int, pkIndexes ...int) []byte {
+	var b frame.Buffer
+	b.WriteInt(response.PreparedKind)
+	b.WriteShortBytes(id)
+
+	b.WritePreparedFlags(frame.GlobalTablesSpec)
+	b.WriteInt(frame.Int(columns))
+	b.WriteInt(frame.Int(len(pkIndexes)))
+	for _, idx := range pkIndexes {
+		b.WriteShort(frame.Short(idx))
+	}
+	b.WriteString("ks")
+	b.WriteString("t")
+	for i := 0; i < columns; i++ {
+		b.WriteString(fmt.Sprintf("c%d", i))
+		b.WriteShort(frame.Short(frame.BlobID))
+	}
+
+	b.WriteResultFlags(frame.NoMetadata)
+	b.WriteInt(0)
+	return b.Bytes()
+}
+
+// VoidResult returns RESULT response body of a statement which returns nothing.
+func VoidResult() []byte {
+	var b frame.Buffer
+	b.WriteInt(response.VoidKind)
+	return b.Bytes()
+}
+
+// ErrorResponse returns ERROR response with code and message, it's valid for codes without additional fields.
+func ErrorResponse(code frame.ErrorCode, msg string) (frame.OpCode, []byte) {
+	var b frame.Buffer
+	b.WriteInt(code)
+	b.WriteString(msg)
+	return frame.OpError, b.Bytes()
+}
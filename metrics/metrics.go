@@ -0,0 +1,253 @@
+// Package metrics exports driver metrics as Prometheus collectors.
+//
+//	m := metrics.New("scylla")
+//	m.Instrument(&cfg)
+//	session, err := scylla.NewSession(cfg)
+//	...
+//	m.SetStatsSource(session)
+//	prometheus.MustRegister(m)
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/mmatczuk/scylla-go-driver"
+	"github.com/mmatczuk/scylla-go-driver/transport"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsSource returns request counters of node connections, it's implemented by scylla.Session.
+type StatsSource interface {
+	Stats() []transport.HostStats
+}
+
+// Metrics records driver events and exposes them as Prometheus metrics.
+// It's a prometheus.Collector and it implements driver observers.
+type Metrics struct {
+	requestDuration  *prometheus.HistogramVec
+	requestErrors    *prometheus.CounterVec
+	requestTimeouts  *prometheus.CounterVec
+	connOpens        *prometheus.CounterVec
+	connFailures     *prometheus.CounterVec
+	streamsExhausted *prometheus.CounterVec
+	pickReplaced     *prometheus.CounterVec
+	breakerChanges   *prometheus.CounterVec
+	refreshes        *prometheus.CounterVec
+	controlReopens   *prometheus.CounterVec
+
+	inFlightDesc    *prometheus.Desc
+	queuedDesc      *prometheus.Desc
+	streamUsageDesc *prometheus.Desc
+
+	// Observers set before Instrument, events are passed to them.
+	connObs    transport.ConnObserver
+	queryObs   transport.QueryObserver
+	clusterObs transport.ClusterObserver
+
+	stats StatsSource
+	mu    sync.Mutex // mu guards stats
+}
+
+var (
+	_ prometheus.Collector      = (*Metrics)(nil)
+	_ transport.ConnObserver    = (*Metrics)(nil)
+	_ transport.StreamsObserver = (*Metrics)(nil)
+	_ transport.BreakerObserver = (*Metrics)(nil)
+	_ transport.QueryObserver   = (*Metrics)(nil)
+	_ transport.ClusterObserver = (*Metrics)(nil)
+)
+
+// New returns Metrics with metric names prefixed with namespace.
+func New(namespace string) *Metrics {
+	hostLabels := []string{"host", "dc"}
+	shardLabels := []string{"host", "shard"}
+
+	counter := func(name, help string, labels ...string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      name,
+			Help:      help,
+		}, labels)
+	}
+
+	return &Metrics{
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Duration of request attempts.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 18),
+		}, hostLabels),
+		requestErrors:    counter("request_errors_total", "Number of failed request attempts.", hostLabels...),
+		requestTimeouts:  counter("request_timeouts_total", "Number of request attempts failed with read or write timeout.", hostLabels...),
+		connOpens:        counter("connection_opens_total", "Number of opened connections.", shardLabels...),
+		connFailures:     counter("connection_failures_total", "Number of failed attempts to open connection.", shardLabels...),
+		streamsExhausted: counter("streams_exhausted_total", "Number of requests which could not get a stream ID at once.", "host", "shard", "rejected"),
+		pickReplaced:     counter("pick_replaced_total", "Number of requests moved to less busy connection.", shardLabels...),
+		breakerChanges:   counter("breaker_state_changes_total", "Number of node circuit breaker state changes.", "host", "state"),
+		refreshes:        counter("topology_refreshes_total", "Number of topology refreshes.", "result"),
+		controlReopens:   counter("control_reopens_total", "Number of attempts to reopen control connection.", "result"),
+
+		inFlightDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "requests_in_flight"),
+			"Number of requests sent and waiting for response.", []string{"host", "dc", "shard"}, nil),
+		queuedDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "requests_queued"),
+			"Number of requests waiting to be sent.", []string{"host", "dc", "shard"}, nil),
+		streamUsageDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "stream_utilization_ratio"),
+			"Fraction of connection stream IDs in use.", []string{"host", "dc", "shard"}, nil),
+	}
+}
+
+// Instrument sets m as observer in cfg, observers already set in cfg are still notified.
+func (m *Metrics) Instrument(cfg *scylla.SessionConfig) {
+	if cfg.ConnObserver != m {
+		m.connObs = cfg.ConnObserver
+		cfg.ConnObserver = m
+	}
+	if cfg.QueryObserver != m {
+		m.queryObs = cfg.QueryObserver
+		cfg.QueryObserver = m
+	}
+	if cfg.ClusterObserver != m {
+		m.clusterObs = cfg.ClusterObserver
+		cfg.ClusterObserver = m
+	}
+}
+
+// SetStatsSource sets source of in flight and queued requests gauges, usually scylla.Session.
+func (m *Metrics) SetStatsSource(s StatsSource) {
+	m.mu.Lock()
+	m.stats = s
+	m.mu.Unlock()
+}
+
+func (m *Metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.requestDuration,
+		m.requestErrors,
+		m.requestTimeouts,
+		m.connOpens,
+		m.connFailures,
+		m.streamsExhausted,
+		m.pickReplaced,
+		m.breakerChanges,
+		m.refreshes,
+		m.controlReopens,
+	}
+}
+
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range m.collectors() {
+		c.Describe(ch)
+	}
+	ch <- m.inFlightDesc
+	ch <- m.queuedDesc
+	ch <- m.streamUsageDesc
+}
+
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range m.collectors() {
+		c.Collect(ch)
+	}
+
+	m.mu.Lock()
+	s := m.stats
+	m.mu.Unlock()
+	if s == nil {
+		return
+	}
+	for _, h := range s.Stats() {
+		for _, c := range h.Conns {
+			labels := []string{h.Host.Addr, h.Host.Datacenter, strconv.Itoa(c.Shard)}
+			ch <- prometheus.MustNewConstMetric(m.inFlightDesc, prometheus.GaugeValue, float64(c.InFlight), labels...)
+			ch <- prometheus.MustNewConstMetric(m.queuedDesc, prometheus.GaugeValue, float64(c.InQueue), labels...)
+			ch <- prometheus.MustNewConstMetric(m.streamUsageDesc, prometheus.GaugeValue, float64(c.InFlight)/transport.MaxStreams, labels...)
+		}
+	}
+}
+
+func (m *Metrics) OnQueryStart(ev transport.QueryEvent) {
+	if m.queryObs != nil {
+		m.queryObs.OnQueryStart(ev)
+	}
+}
+
+func (m *Metrics) OnQueryEnd(ev transport.QueryEndEvent) {
+	host, dc := ev.Host.Addr, ev.Host.Datacenter
+	m.requestDuration.WithLabelValues(host, dc).Observe(ev.Latency.Seconds())
+	if ev.Err != nil {
+		m.requestErrors.WithLabelValues(host, dc).Inc()
+	}
+	if transport.IsTimeout(ev.Err) {
+		m.requestTimeouts.WithLabelValues(host, dc).Inc()
+	}
+	if m.queryObs != nil {
+		m.queryObs.OnQueryEnd(ev)
+	}
+}
+
+func (m *Metrics) OnConnect(ev transport.ConnectEvent) {
+	if ev.Err != nil {
+		m.connFailures.WithLabelValues(connLabels(ev.ConnEvent)...).Inc()
+	} else {
+		m.connOpens.WithLabelValues(connLabels(ev.ConnEvent)...).Inc()
+	}
+	if m.connObs != nil {
+		m.connObs.OnConnect(ev)
+	}
+}
+
+func (m *Metrics) OnPickReplacedWithLessBusyConn(ev transport.ConnEvent) {
+	m.pickReplaced.WithLabelValues(connLabels(ev)...).Inc()
+	if m.connObs != nil {
+		m.connObs.OnPickReplacedWithLessBusyConn(ev)
+	}
+}
+
+func (m *Metrics) OnStreamsExhausted(ev transport.StreamsExhaustedEvent) {
+	m.streamsExhausted.WithLabelValues(append(connLabels(ev.ConnEvent), strconv.FormatBool(ev.Rejected))...).Inc()
+	if o, ok := m.connObs.(transport.StreamsObserver); ok {
+		o.OnStreamsExhausted(ev)
+	}
+}
+
+func (m *Metrics) OnBreakerStateChange(ev transport.BreakerEvent) {
+	m.breakerChanges.WithLabelValues(ev.Addr, ev.To.String()).Inc()
+	if o, ok := m.connObs.(transport.BreakerObserver); ok {
+		o.OnBreakerStateChange(ev)
+	}
+}
+
+func (m *Metrics) OnTopologyRefresh(ev transport.TopologyRefreshEvent) {
+	m.refreshes.WithLabelValues(result(ev.Err)).Inc()
+	if m.clusterObs != nil {
+		m.clusterObs.OnTopologyRefresh(ev)
+	}
+}
+
+func (m *Metrics) OnControlReopen(ev transport.ControlReopenEvent) {
+	m.controlReopens.WithLabelValues(result(ev.Err)).Inc()
+	if m.clusterObs != nil {
+		m.clusterObs.OnControlReopen(ev)
+	}
+}
+
+// connLabels returns host and shard labels, connection address port is dropped.
+func connLabels(ev transport.ConnEvent) []string {
+	host := ev.Addr
+	if h, _, err := net.SplitHostPort(ev.Addr); err == nil {
+		host = h
+	}
+	shard := "unknown"
+	if ev.Shard != transport.UnknownShard {
+		shard = strconv.Itoa(int(ev.Shard))
+	}
+	return []string{host, shard}
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver"
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/frame/response"
+	"github.com/mmatczuk/scylla-go-driver/transport"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type staticStats []transport.HostStats
+
+func (s staticStats) Stats() []transport.HostStats {
+	return s
+}
+
+type countingConnObserver struct {
+	transport.LoggingConnObserver
+	connects int
+}
+
+func (o *countingConnObserver) OnConnect(ev transport.ConnectEvent) {
+	o.connects++
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := New("scylla")
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m)
+
+	next := &countingConnObserver{LoggingConnObserver: transport.LoggingConnObserver{Logger: transport.NopLogger{}}}
+	cfg := scylla.DefaultSessionConfig("")
+	cfg.ConnObserver = next
+	m.Instrument(&cfg)
+	if cfg.ConnObserver != m || cfg.QueryObserver != m || cfg.ClusterObserver != m {
+		t.Fatal("observers not set")
+	}
+
+	host := transport.HostInfo{Addr: "10.0.0.1", Datacenter: "waw"}
+	timeout := response.ReadTimeoutError{ScyllaError: response.ScyllaError{Code: frame.ErrCodeReadTimeout}}
+
+	m.OnQueryStart(transport.QueryEvent{Host: host, Attempt: 1, Page: 1})
+	m.OnQueryEnd(transport.QueryEndEvent{QueryEvent: transport.QueryEvent{Host: host, Attempt: 1, Page: 1}, Latency: time.Millisecond, Rows: 1})
+	m.OnQueryEnd(transport.QueryEndEvent{QueryEvent: transport.QueryEvent{Host: host, Attempt: 1, Page: 1}, Latency: time.Second, Err: timeout})
+	m.OnConnect(transport.ConnectEvent{ConnEvent: transport.ConnEvent{Addr: "10.0.0.1:19042", Shard: 1}})
+	m.OnConnect(transport.ConnectEvent{ConnEvent: transport.ConnEvent{Addr: "10.0.0.1:19042", Shard: 2}, Err: errors.New("dial")})
+	m.OnStreamsExhausted(transport.StreamsExhaustedEvent{ConnEvent: transport.ConnEvent{Addr: "10.0.0.1:19042", Shard: 1}, Rejected: true})
+	m.OnTopologyRefresh(transport.TopologyRefreshEvent{})
+	m.OnControlReopen(transport.ControlReopenEvent{Err: errors.New("dial")})
+	m.SetStatsSource(staticStats{{
+		Host:  host,
+		Conns: []transport.ConnStats{{Shard: 1, InFlight: transport.MaxStreams / 2, InQueue: 3}},
+	}})
+
+	if next.connects != 2 {
+		t.Fatalf("got %d connect events passed to previous observer, expected 2", next.connects)
+	}
+
+	if n := testutil.CollectAndCount(m, "scylla_request_duration_seconds"); n != 1 {
+		t.Fatalf("got %d request duration series, expected 1", n)
+	}
+
+	expected := `
+# HELP scylla_connection_failures_total Number of failed attempts to open connection.
+# TYPE scylla_connection_failures_total counter
+scylla_connection_failures_total{host="10.0.0.1",shard="2"} 1
+# HELP scylla_connection_opens_total Number of opened connections.
+# TYPE scylla_connection_opens_total counter
+scylla_connection_opens_total{host="10.0.0.1",shard="1"} 1
+# HELP scylla_control_reopens_total Number of attempts to reopen control connection.
+# TYPE scylla_control_reopens_total counter
+scylla_control_reopens_total{result="error"} 1
+# HELP scylla_request_errors_total Number of failed request attempts.
+# TYPE scylla_request_errors_total counter
+scylla_request_errors_total{dc="waw",host="10.0.0.1"} 1
+# HELP scylla_request_timeouts_total Number of request attempts failed with read or write timeout.
+# TYPE scylla_request_timeouts_total counter
+scylla_request_timeouts_total{dc="waw",host="10.0.0.1"} 1
+# HELP scylla_requests_in_flight Number of requests sent and waiting for response.
+# TYPE scylla_requests_in_flight gauge
+scylla_requests_in_flight{dc="waw",host="10.0.0.1",shard="1"} 16383
+# HELP scylla_requests_queued Number of requests waiting to be sent.
+# TYPE scylla_requests_queued gauge
+scylla_requests_queued{dc="waw",host="10.0.0.1",shard="1"} 3
+# HELP scylla_streams_exhausted_total Number of requests which could not get a stream ID at once.
+# TYPE scylla_streams_exhausted_total counter
+scylla_streams_exhausted_total{host="10.0.0.1",rejected="true",shard="1"} 1
+# HELP scylla_topology_refreshes_total Number of topology refreshes.
+# TYPE scylla_topology_refreshes_total counter
+scylla_topology_refreshes_total{result="ok"} 1
+`
+	names := []string{
+		"scylla_connection_failures_total",
+		"scylla_connection_opens_total",
+		"scylla_control_reopens_total",
+		"scylla_request_errors_total",
+		"scylla_request_timeouts_total",
+		"scylla_requests_in_flight",
+		"scylla_requests_queued",
+		"scylla_streams_exhausted_total",
+		"scylla_topology_refreshes_total",
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected), names...); err != nil {
+		t.Fatal(err)
+	}
+}
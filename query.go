@@ -2,6 +2,7 @@ package scylla
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
 	"github.com/mmatczuk/scylla-go-driver/transport"
@@ -13,26 +14,62 @@ type Query struct {
 	buf       frame.Buffer
 	exec      func(*transport.Conn, transport.Statement, frame.Bytes) (transport.QueryResult, error)
 	asyncExec func(*transport.Conn, transport.Statement, frame.Bytes, transport.ResponseHandler)
-	res       []transport.ResponseHandler
+	res       []asyncResult
+
+	routingToken    transport.Token
+	hasRoutingToken bool
+
+	host     string
+	shard    int
+	hasShard bool
+}
+
+// asyncResult holds the node the asynchronous request was sent to, in order to report the result to it.
+type asyncResult struct {
+	h       transport.ResponseHandler
+	node    *transport.Node
+	attempt queryAttempt
 }
 
 func (q *Query) Exec() (Result, error) {
-	conn, err := q.pickConn()
+	if !q.session.acquire() {
+		return Result{}, ErrSessionClosed
+	}
+	defer q.session.release()
+
+	n, conn, err := q.pickConn()
 	if err != nil {
 		return Result{}, err
 	}
+	defer q.session.releaseAdmission(n)
 
+	a := q.session.startAttempt(&q.stmt, n, conn, firstAttempt, firstPage)
 	res, err := q.exec(conn, q.stmt, nil)
+	q.session.endAttempt(a, time.Now(), len(res.Rows), err)
+	n.ReportResult(err)
 	return Result(res), err
 }
 
-func (q *Query) pickConn() (*transport.Conn, error) {
+func (q *Query) pickConn() (*transport.Node, *transport.Conn, error) {
+	if q.host != "" {
+		return q.pinnedConn()
+	}
+	if q.hasShard {
+		return nil, nil, fmt.Errorf("shard %d set without host", q.shard)
+	}
+
 	token, tokenAware := q.token()
 	info, err := q.info(token, tokenAware)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	n := q.session.policy.Node(info, 0)
+	if n == nil {
+		return nil, nil, errNoConnection
+	}
+	if err := q.session.admit(n); err != nil {
+		return nil, nil, err
+	}
 
 	var conn *transport.Conn
 	if tokenAware {
@@ -41,23 +78,59 @@ func (q *Query) pickConn() (*transport.Conn, error) {
 		conn = n.LeastBusyConn()
 	}
 	if conn == nil {
-		return nil, errNoConnection
+		q.session.releaseAdmission(n)
+		return nil, nil, errNoConnection
 	}
 
-	return conn, nil
+	return n, conn, nil
 }
 
+// pinnedConn returns connection to the host set with SetHost bypassing host selection policy.
+func (q *Query) pinnedConn() (*transport.Node, *transport.Conn, error) {
+	n, err := q.session.cluster.Node(q.host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := q.session.admit(n); err != nil {
+		return nil, nil, err
+	}
+
+	if q.hasShard {
+		conn, err := n.ShardConn(q.shard)
+		if err != nil {
+			q.session.releaseAdmission(n)
+			return nil, nil, err
+		}
+		return n, conn, nil
+	}
+	conn := n.LeastBusyConn()
+	if conn == nil {
+		q.session.releaseAdmission(n)
+		return nil, nil, fmt.Errorf("%w: %s", ErrHostNotConnected, q.host)
+	}
+	return n, conn, nil
+}
+
+// AsyncExec sends the query without waiting for the result, the result has to be read with Fetch.
+// With admission control enabled the request holds its slot until the result is fetched.
 func (q *Query) AsyncExec() {
+	if !q.session.acquire() {
+		q.res = append(q.res, asyncResult{h: transport.MakeResponseHandlerWithError(ErrSessionClosed)})
+		return
+	}
+	defer q.session.release()
+
 	stmt := q.stmt.Clone()
 
-	conn, err := q.pickConn()
+	n, conn, err := q.pickConn()
 	if err != nil {
-		q.res = append(q.res, transport.MakeResponseHandlerWithError(err))
+		q.res = append(q.res, asyncResult{h: transport.MakeResponseHandlerWithError(err)})
 		return
 	}
 
 	h := transport.MakeResponseHandler()
-	q.res = append(q.res, h)
+	a := q.session.startAttempt(&stmt, n, conn, firstAttempt, firstPage)
+	q.res = append(q.res, asyncResult{h: h, node: n, attempt: a})
 	q.asyncExec(conn, stmt, nil, h)
 }
 
@@ -69,20 +142,77 @@ func (q *Query) Fetch() (Result, error) {
 		return Result{}, ErrNoQueryResults
 	}
 
-	h := q.res[0]
+	r := q.res[0]
 	q.res = q.res[1:]
 
-	resp := <-h
+	resp := <-r.h
+	// Latency is measured until the response was received, not until it is fetched.
+	end := resp.Received
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if r.node != nil {
+		q.session.releaseAdmission(r.node)
+	}
 	if resp.Err != nil {
+		if r.node != nil {
+			q.session.endAttempt(r.attempt, end, 0, resp.Err)
+			r.node.ReportResult(resp.Err)
+		}
 		return Result{}, resp.Err
 	}
 
 	res, err := transport.MakeQueryResult(resp.Response, q.stmt.Metadata)
+	if r.node != nil {
+		q.session.endAttempt(r.attempt, end, len(res.Rows), err)
+		r.node.ReportResult(err)
+	}
 	return Result(res), err
 }
 
+// SetHost makes the query run on the node with given address or host ID bypassing
+// host selection policy, empty host restores routing with the policy and clears shard set with SetShard.
+func (q *Query) SetHost(host string) *Query {
+	q.host = host
+	if host == "" {
+		q.shard = 0
+		q.hasShard = false
+	}
+	return q
+}
+
+// SetShard makes the query run on given shard of the node set with SetHost.
+func (q *Query) SetShard(shard int) *Query {
+	q.shard = shard
+	q.hasShard = true
+	return q
+}
+
+// SetRoutingKey sets partition key used to route the query, values are serialized values
+// of partition key columns in order. It takes precedence over partition key of prepared statement.
+func (q *Query) SetRoutingKey(values ...[]byte) *Query {
+	if len(values) == 0 {
+		q.hasRoutingToken = false
+		return q
+	}
+
+	q.buf.Reset()
+	return q.SetRoutingToken(partitionKeyToken(&q.buf, values))
+}
+
+// SetRoutingToken sets token used to route the query, it takes precedence over partition key
+// of prepared statement and routing key.
+func (q *Query) SetRoutingToken(token transport.Token) *Query {
+	q.routingToken = token
+	q.hasRoutingToken = true
+	return q
+}
+
 // https://github.com/scylladb/scylla/blob/40adf38915b6d8f5314c621a94d694d172360833/compound_compat.hh#L33-L47
 func (q *Query) token() (transport.Token, bool) {
+	if q.hasRoutingToken {
+		return q.routingToken, true
+	}
 	if q.stmt.PkCnt == 0 {
 		return 0, false
 	}
@@ -92,15 +222,32 @@ func (q *Query) token() (transport.Token, bool) {
 		return transport.MurmurToken(q.stmt.Values[q.stmt.PkIndexes[0]].Bytes), true
 	}
 	for _, idx := range q.stmt.PkIndexes {
-		size := q.stmt.Values[idx].N
-		q.buf.WriteShort(frame.Short(size))
-		q.buf.Write(q.stmt.Values[idx].Bytes)
-		q.buf.WriteByte(0)
+		writeCompositeKeyComponent(&q.buf, q.stmt.Values[idx].Bytes)
 	}
 
 	return transport.MurmurToken(q.buf.Bytes()), true
 }
 
+// partitionKeyToken returns token of partition key given as serialized values of its columns,
+// b is used to compose compound keys.
+func partitionKeyToken(b *frame.Buffer, values [][]byte) transport.Token {
+	if len(values) == 1 {
+		return transport.MurmurToken(values[0])
+	}
+	for _, v := range values {
+		writeCompositeKeyComponent(b, v)
+	}
+	return transport.MurmurToken(b.Bytes())
+}
+
+// writeCompositeKeyComponent writes a single component of compound partition key
+// in the format used by Cassandra to compute token.
+func writeCompositeKeyComponent(b *frame.Buffer, v []byte) {
+	b.WriteShort(frame.Short(len(v)))
+	b.Write(v)
+	b.WriteByte(0)
+}
+
 func (q *Query) info(token transport.Token, tokenAware bool) (transport.QueryInfo, error) {
 	if tokenAware {
 		// TODO: Will the driver support using different keyspaces than default?
@@ -148,6 +295,8 @@ func (q *Query) Compression() bool {
 
 type Result transport.QueryResult
 
+// Iter returns iterator over all result pages, it counts as in flight request until it's closed
+// or read to the end, Session.Close waits for it.
 func (q *Query) Iter() Iter {
 	it := Iter{
 		requestCh: make(chan struct{}, 1),
@@ -155,14 +304,22 @@ func (q *Query) Iter() Iter {
 		errCh:     make(chan error, 1),
 	}
 
-	conn, err := q.pickConn()
+	if !q.session.acquire() {
+		it.errCh <- ErrSessionClosed
+		return it
+	}
+
+	n, conn, err := q.pickConn()
 	if err != nil {
+		q.session.release()
 		it.errCh <- err
 		return it
 	}
 
 	worker := iterWorker{
 		stmt:      q.stmt.Clone(),
+		session:   q.session,
+		node:      n,
 		conn:      conn,
 		queryExec: q.exec,
 		requestCh: it.requestCh,
@@ -230,6 +387,8 @@ func (it *Iter) Close() {
 
 type iterWorker struct {
 	stmt        transport.Statement
+	session     *Session
+	node        *transport.Node
 	conn        *transport.Conn
 	pagingState []byte
 	queryExec   func(*transport.Conn, transport.Statement, frame.Bytes) (transport.QueryResult, error)
@@ -239,20 +398,55 @@ type iterWorker struct {
 	errCh     chan error
 }
 
+// loop fetches pages on request, session close waits for it to exit i.e. for the iter to be closed or exhausted.
+// If session close times out loop exits with ErrSessionClosed.
 func (w *iterWorker) loop() {
+	defer w.session.release()
+
+	// Slot for the first page is reserved by Query.Iter.
+	admitted := true
+	page := firstPage
 	for {
-		_, ok := <-w.requestCh
+		var ok bool
+		select {
+		case _, ok = <-w.requestCh:
+		case <-w.session.done:
+			w.errCh <- ErrSessionClosed
+		}
 		if !ok {
+			if admitted {
+				w.session.releaseAdmission(w.node)
+			}
 			return
 		}
+		if !admitted {
+			if err := w.session.admit(w.node); err != nil {
+				w.errCh <- err
+				return
+			}
+		}
 
+		a := w.session.startAttempt(&w.stmt, w.node, w.conn, firstAttempt, page)
 		res, err := w.queryExec(w.conn, w.stmt, w.pagingState)
+		w.session.endAttempt(a, time.Now(), len(res.Rows), err)
+		w.node.ReportResult(err)
+		w.session.releaseAdmission(w.node)
+		admitted = false
+		page++
 		if err != nil {
 			w.errCh <- err
 			return
 		}
 		w.pagingState = res.PagingState
-		w.nextCh <- res
+		select {
+		case w.nextCh <- res:
+		case <-w.requestCh:
+			// Iter was closed before the page was read.
+			return
+		case <-w.session.done:
+			w.errCh <- ErrSessionClosed
+			return
+		}
 
 		if !res.HasMorePages {
 			w.errCh <- ErrNoMoreRows
@@ -1,11 +1,14 @@
 package scylla
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"sync"
+	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
 	"github.com/mmatczuk/scylla-go-driver/transport"
+	"go.uber.org/atomic"
 )
 
 // TODO: Add retry policy.
@@ -19,6 +22,58 @@ const (
 	SchemaChange   EventType = "SCHEMA_CHANGE"
 )
 
+type (
+	ClusterEvent        = transport.ClusterEvent
+	TopologyChangeEvent = transport.TopologyChangeEvent
+	StatusChangeEvent   = transport.StatusChangeEvent
+	SchemaChangeEvent   = transport.SchemaChangeEvent
+	EventHandler        = transport.EventHandler
+
+	HostInfo     = transport.HostInfo
+	HostListener = transport.HostListener
+
+	AdmissionConfig = transport.AdmissionConfig
+	OverloadedError = transport.OverloadedError
+
+	LatencyAwareConfig = transport.LatencyAwareConfig
+	PolicyOption       = transport.PolicyOption
+	HostFilter         = transport.HostFilter
+
+	Token       = transport.Token
+	ReplicaInfo = transport.ReplicaInfo
+
+	AddressTranslator       = transport.AddressTranslator
+	StaticAddressTranslator = transport.StaticAddressTranslator
+	SNIProxyConfig          = transport.SNIProxyConfig
+	SNIProxyDatacenter      = transport.SNIProxyDatacenter
+
+	Dialer          = transport.Dialer
+	LocalAddrDialer = transport.LocalAddrDialer
+	DialerFunc      = transport.DialerFunc
+
+	Authenticator         = transport.Authenticator
+	AuthProvider          = transport.AuthProvider
+	AuthProviderFunc      = transport.AuthProviderFunc
+	PasswordAuthenticator = transport.PasswordAuthenticator
+	CredentialsProvider   = transport.CredentialsProvider
+	CertificateReloader   = transport.CertificateReloader
+
+	QueryObserver = transport.QueryObserver
+	QueryEvent    = transport.QueryEvent
+	QueryEndEvent = transport.QueryEndEvent
+
+	ClusterObserver      = transport.ClusterObserver
+	TopologyRefreshEvent = transport.TopologyRefreshEvent
+	ControlReopenEvent   = transport.ControlReopenEvent
+
+	ConnStats = transport.ConnStats
+	HostStats = transport.HostStats
+
+	Logger    = transport.Logger
+	StdLogger = transport.StdLogger
+	NopLogger = transport.NopLogger
+)
+
 type Consistency = uint16
 
 const (
@@ -54,6 +109,15 @@ var (
 		"LOCALSERIAL Consistency = 0x0009\n" +
 		"LOCALONE    Consistency = 0x000A")
 	errNoConnection = fmt.Errorf("no working connection")
+
+	// ErrOverloaded is returned when request is rejected by admission control.
+	ErrOverloaded = transport.ErrOverloaded
+	// ErrNoSuchHost is returned when query host set with Query.SetHost is not known.
+	ErrNoSuchHost = transport.ErrNoSuchHost
+	// ErrHostNotConnected is returned when query host or shard set with Query.SetHost is not connected.
+	ErrHostNotConnected = transport.ErrHostNotConnected
+	// ErrSessionClosed is returned for requests made after Session.Close was called.
+	ErrSessionClosed = fmt.Errorf("session closed")
 )
 
 type Compression = frame.Compression
@@ -67,6 +131,10 @@ type SessionConfig struct {
 	Hosts  []string
 	Events []EventType
 	Policy transport.HostSelectionPolicy
+	// Admission limits the number of concurrent requests, it's disabled by default.
+	Admission AdmissionConfig
+	// QueryObserver is notified about every request attempt, it's optional.
+	QueryObserver QueryObserver
 	transport.ConnConfig
 }
 
@@ -108,9 +176,15 @@ func (cfg *SessionConfig) Validate() error {
 }
 
 type Session struct {
-	cfg     SessionConfig
-	cluster *transport.Cluster
-	policy  transport.HostSelectionPolicy
+	cfg       SessionConfig
+	cluster   *transport.Cluster
+	policy    transport.HostSelectionPolicy
+	admission *transport.AdmissionController
+
+	closed    atomic.Bool
+	requests  atomic.Int32  // requests which are being sent
+	done      chan struct{} // closed when session is closed, stops iterators which were not closed
+	closeOnce sync.Once
 }
 
 func NewSession(cfg SessionConfig) (*Session, error) {
@@ -119,6 +193,14 @@ func NewSession(cfg SessionConfig) (*Session, error) {
 	if err := cfg.Validate(); err != nil {
 		return nil, err
 	}
+	if cfg.Logger == nil {
+		cfg.Logger = StdLogger{}
+	}
+
+	admission := transport.NewAdmissionController(cfg.Admission)
+	if admission != nil {
+		cfg.HostListener = admission.HostListener(cfg.HostListener)
+	}
 
 	cluster, err := transport.NewCluster(cfg.ConnConfig, cfg.Policy, cfg.Events, cfg.Hosts...)
 	if err != nil {
@@ -126,9 +208,11 @@ func NewSession(cfg SessionConfig) (*Session, error) {
 	}
 
 	s := &Session{
-		cfg:     cfg,
-		cluster: cluster,
-		policy:  cfg.Policy,
+		cfg:       cfg,
+		cluster:   cluster,
+		policy:    cfg.Policy,
+		admission: admission,
+		done:      make(chan struct{}),
 	}
 
 	return s, nil
@@ -147,6 +231,11 @@ func (s *Session) Query(content string) Query {
 }
 
 func (s *Session) Prepare(content string) (Query, error) {
+	if !s.acquire() {
+		return Query{}, ErrSessionClosed
+	}
+	defer s.release()
+
 	n := s.policy.Node(s.cluster.NewQueryInfo(), 0)
 	conn := n.LeastBusyConn()
 	if conn == nil {
@@ -167,15 +256,236 @@ func (s *Session) Prepare(content string) (Query, error) {
 	}, err
 }
 
+// Subscribe registers handler for cluster events listed in SessionConfig.Events,
+// events are delivered after the driver has applied them to its topology.
+// It returns a function that removes the handler.
+func (s *Session) Subscribe(h EventHandler) (unsubscribe func()) {
+	return s.cluster.Subscribe(h)
+}
+
+// Hosts returns snapshot of all nodes known to the session.
+func (s *Session) Hosts() []HostInfo {
+	return s.cluster.Hosts()
+}
+
+// Stats returns snapshot of request counters of connections to every node known to the session.
+func (s *Session) Stats() []HostStats {
+	return s.cluster.Stats()
+}
+
+// Token returns token of partition key given as serialized values of partition key columns in order,
+// compound keys are composed the same way as in queries.
+func (s *Session) Token(keyspace string, pkValues ...[]byte) (Token, error) {
+	if len(pkValues) == 0 {
+		return 0, fmt.Errorf("no partition key values given")
+	}
+	if err := s.cluster.CheckKeyspace(keyspace); err != nil {
+		return 0, err
+	}
+
+	var b frame.Buffer
+	return partitionKeyToken(&b, pkValues), nil
+}
+
+// Replicas returns replicas of token in keyspace with shards owning the token, local datacenter replicas go first.
+// If keyspace is empty the session keyspace is used.
+func (s *Session) Replicas(keyspace string, token Token) ([]ReplicaInfo, error) {
+	return s.cluster.Replicas(keyspace, token)
+}
+
+// HostResult is a result of a query run on a single node.
+type HostResult struct {
+	Host   HostInfo
+	Result Result
+	Err    error
+}
+
+// Broadcast runs q concurrently on every node known to the session bypassing host selection policy.
+// Results are in the same order as Hosts, shard set with Query.SetShard is respected.
+func (s *Session) Broadcast(q Query) []HostResult {
+	hosts := s.cluster.Hosts()
+	res := make([]HostResult, len(hosts))
+
+	var wg sync.WaitGroup
+	for i := range hosts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			hq := q
+			hq.stmt = q.stmt.Clone()
+			hq.buf = frame.Buffer{}
+			hq.res = nil
+			hq.SetHost(hosts[i].Addr)
+
+			r, err := hq.Exec()
+			res[i] = HostResult{
+				Host:   hosts[i],
+				Result: r,
+				Err:    err,
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	return res
+}
+
 func (s *Session) NewTokenAwarePolicy() transport.HostSelectionPolicy {
 	return transport.NewTokenAwarePolicy("")
 }
 
-func (s *Session) NewTokenAwareDCAwarePolicy(localDC string) transport.HostSelectionPolicy {
-	return transport.NewTokenAwarePolicy(localDC)
+func (s *Session) NewTokenAwareDCAwarePolicy(localDC string, opts ...PolicyOption) transport.HostSelectionPolicy {
+	return transport.NewTokenAwarePolicy(localDC, opts...)
+}
+
+func (s *Session) NewRoundRobinPolicy() transport.HostSelectionPolicy {
+	return transport.NewRoundRobinPolicy()
+}
+
+func (s *Session) NewDCAwareRoundRobinPolicy(localDC string, opts ...PolicyOption) transport.HostSelectionPolicy {
+	return transport.NewDCAwareRoundRobinPolicy(localDC, opts...)
+}
+
+// NewFilterPolicy removes nodes rejected by filter from plans of p.
+func (s *Session) NewFilterPolicy(p transport.HostSelectionPolicy, filter HostFilter) transport.HostSelectionPolicy {
+	return transport.NewFilterPolicy(p, filter)
+}
+
+// NewRackAwarePolicy returns token aware policy which prefers replicas in localRack, then in localDC.
+func (s *Session) NewRackAwarePolicy(localDC, localRack string, opts ...PolicyOption) transport.HostSelectionPolicy {
+	return transport.NewRackAwarePolicy(localDC, localRack, opts...)
+}
+
+// NewLatencyAwarePolicy wraps p so that nodes much slower than the fastest node are tried last.
+func (s *Session) NewLatencyAwarePolicy(p transport.HostSelectionPolicy, cfg LatencyAwareConfig) transport.HostSelectionPolicy {
+	return transport.NewLatencyAwarePolicy(p, cfg)
+}
+
+// firstAttempt is the number of the first attempt of running a statement,
+// requests are not retried nor executed speculatively yet.
+const firstAttempt = 1
+
+// firstPage is the number of the first result page, only iterators fetch the next pages.
+const firstPage = 1
+
+// queryAttempt holds data needed to report end of an attempt to QueryObserver.
+type queryAttempt struct {
+	ev    QueryEvent
+	node  *transport.Node
+	start time.Time
+}
+
+// startAttempt reports start of fetching page of stmt results on conn to QueryObserver.
+func (s *Session) startAttempt(stmt *transport.Statement, n *transport.Node, conn *transport.Conn, attempt, page int) queryAttempt {
+	a := queryAttempt{node: n, start: time.Now()}
+	if s.cfg.QueryObserver == nil {
+		return a
+	}
+
+	a.ev = QueryEvent{
+		Statement:   stmt.Content,
+		Host:        n.Info(),
+		Shard:       conn.Shard(),
+		Consistency: stmt.Consistency,
+		Attempt:     attempt,
+		Page:        page,
+	}
+	s.cfg.QueryObserver.OnQueryStart(a.ev)
+	return a
+}
+
+// endAttempt reports end of attempt to QueryObserver, latency of successful attempt
+// is also reported to the policy if it's latency aware.
+func (s *Session) endAttempt(a queryAttempt, end time.Time, rows int, err error) {
+	latency := end.Sub(a.start)
+	if err == nil {
+		s.trackLatency(a.node, latency)
+	}
+	if s.cfg.QueryObserver != nil {
+		s.cfg.QueryObserver.OnQueryEnd(QueryEndEvent{
+			QueryEvent: a.ev,
+			Latency:    latency,
+			Rows:       rows,
+			Err:        err,
+		})
+	}
+}
+
+// trackLatency reports latency of a request if the policy is latency aware.
+func (s *Session) trackLatency(n *transport.Node, latency time.Duration) {
+	if t, ok := s.policy.(transport.LatencyTracker); ok {
+		t.TrackLatency(n, latency)
+	}
 }
 
-func (s *Session) Close() {
-	log.Println("session: close")
+// NewCertificateReloader returns client certificate source which reloads certFile and keyFile when they change,
+// set its GetClientCertificate method as TLSConfig.GetClientCertificate.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	return transport.NewCertificateReloader(certFile, keyFile)
+}
+
+// RotateConnections gradually replaces all connections with new ones waiting pause between connections,
+// so that they pick up new credentials or certificates. In flight requests are not interrupted.
+// It blocks for about pause times number of connections, call it in a separate goroutine if needed.
+func (s *Session) RotateConnections(pause time.Duration) {
+	s.cluster.RotateConnections(pause)
+}
+
+// admit reserves admission slot for a request to n, it's a no-op if admission control is disabled.
+func (s *Session) admit(n *transport.Node) error {
+	if s.admission == nil {
+		return nil
+	}
+	return s.admission.Admit(n)
+}
+
+// releaseAdmission frees the slot reserved by admit.
+func (s *Session) releaseAdmission(n *transport.Node) {
+	if s.admission != nil {
+		s.admission.Release(n)
+	}
+}
+
+// acquire registers request being sent, it returns false if session is closed.
+func (s *Session) acquire() bool {
+	s.requests.Inc()
+	if s.closed.Load() {
+		s.requests.Dec()
+		return false
+	}
+	return true
+}
+
+func (s *Session) release() {
+	s.requests.Dec()
+}
+
+const closePollInterval = 10 * time.Millisecond
+
+// Close stops accepting new requests and waits for in flight requests to finish until ctx is done,
+// then it closes all connections and waits for the driver go routines to exit.
+// It returns ctx error if in flight requests did not finish in time, iterators which were not closed
+// are stopped then and return ErrSessionClosed.
+func (s *Session) Close(ctx context.Context) error {
+	s.cfg.Logger.Info("session: close")
+	s.closed.Store(true)
+
+	err := s.drain(ctx)
+	s.closeOnce.Do(func() { close(s.done) })
 	s.cluster.Close()
+	return err
+}
+
+func (s *Session) drain(ctx context.Context) error {
+	ticker := time.NewTicker(closePollInterval)
+	defer ticker.Stop()
+	for s.requests.Load() > 0 || s.cluster.Waiting() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("drain requests: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
 }
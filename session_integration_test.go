@@ -3,10 +3,13 @@
 package scylla
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"sync"
 	"testing"
 
 	"go.uber.org/goleak"
@@ -30,7 +33,7 @@ func initKeyspace(t testing.TB) {
 	if _, err = q.Exec(); err != nil {
 		t.Fatal(err)
 	}
-	s.Close()
+	s.Close(context.Background())
 }
 
 func newTestSession(t testing.TB) *Session {
@@ -47,7 +50,7 @@ func newTestSession(t testing.TB) *Session {
 func TestSessionIntegration(t *testing.T) {
 	defer goleak.VerifyNone(t)
 	session := newTestSession(t)
-	defer session.Close()
+	defer session.Close(context.Background())
 
 	stmts := []string{
 		"CREATE KEYSPACE IF NOT EXISTS mykeyspace WITH replication = {'class': 'SimpleStrategy', 'replication_factor' : 1}",
@@ -96,7 +99,7 @@ const (
 func TestSessionPrepareIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
 	defer goleak.VerifyNone(t)
 	session := newTestSession(t)
-	defer session.Close()
+	defer session.Close(context.Background())
 
 	initStmts := []string{
 		"CREATE KEYSPACE IF NOT EXISTS mykeyspace WITH replication = {'class': 'SimpleStrategy', 'replication_factor' : 1}",
@@ -155,7 +158,7 @@ func TestSessionPrepareIntegration(t *testing.T) { // nolint:paralleltest // Int
 func TestSessionIterIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
 	defer goleak.VerifyNone(t)
 	session := newTestSession(t)
-	defer session.Close()
+	defer session.Close(context.Background())
 
 	initStmts := []string{
 		"CREATE KEYSPACE IF NOT EXISTS mykeyspace WITH replication = {'class': 'SimpleStrategy', 'replication_factor' : 1}",
@@ -338,3 +341,178 @@ func TestTLSIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionRoutingKeyIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
+	defer goleak.VerifyNone(t)
+	session := newTestSession(t)
+	defer session.Close(context.Background())
+
+	q := session.Query("CREATE TABLE IF NOT EXISTS mykeyspace.triples (pk bigint PRIMARY KEY, v1 bigint, v2 bigint)")
+	if _, err := q.Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	selectQuery, err := session.Prepare(selectStmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(0); i < 10; i++ {
+		selectQuery.BindInt64(0, i)
+		expected, ok := selectQuery.token()
+		if !ok {
+			t.Fatal("expected prepared query to be token aware")
+		}
+
+		pk := []byte{0, 0, 0, 0, 0, 0, 0, byte(i)}
+		simple := session.Query(fmt.Sprintf("SELECT v1, v2 FROM mykeyspace.triples WHERE pk = %d", i))
+		simple.SetRoutingKey(pk)
+		if token, ok := simple.token(); !ok || token != expected {
+			t.Fatalf("got token %d, expected %d", token, expected)
+		}
+		if _, err := simple.Exec(); err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := session.Token("mykeyspace", pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != expected {
+			t.Fatalf("Session.Token: got %d, expected %d", token, expected)
+		}
+		replicas, err := session.Replicas("mykeyspace", token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(replicas) != 1 {
+			t.Fatalf("expected 1 replica, got %+v", replicas)
+		}
+	}
+}
+
+func TestSessionSetHostIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
+	defer goleak.VerifyNone(t)
+	session := newTestSession(t)
+	defer session.Close(context.Background())
+
+	for _, h := range session.Hosts() {
+		q := session.Query("SELECT host_id FROM system.local")
+		q.SetHost(h.Addr).SetShard(0)
+		res, err := q.Exec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Rows) != 1 {
+			t.Fatalf("expected 1 row, got %d", len(res.Rows))
+		}
+		hostID, err := res.Rows[0][0].AsUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hostID != h.HostID {
+			t.Fatalf("query run on host %v, expected %v", hostID, h.HostID)
+		}
+	}
+
+	q := session.Query("SELECT host_id FROM system.local")
+	q.SetHost("10.0.0.1")
+	if _, err := q.Exec(); !errors.Is(err, ErrNoSuchHost) {
+		t.Fatalf("got error %v, expected %v", err, ErrNoSuchHost)
+	}
+}
+
+func TestSessionBroadcastIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
+	defer goleak.VerifyNone(t)
+	session := newTestSession(t)
+	defer session.Close(context.Background())
+
+	hosts := session.Hosts()
+	res := session.Broadcast(session.Query("SELECT host_id FROM system.local"))
+	if len(res) != len(hosts) {
+		t.Fatalf("got %d results, expected %d", len(res), len(hosts))
+	}
+	for _, r := range res {
+		if r.Err != nil {
+			t.Fatalf("host %s: %v", r.Host.Addr, r.Err)
+		}
+		if len(r.Result.Rows) != 1 {
+			t.Fatalf("host %s: expected 1 row, got %d", r.Host.Addr, len(r.Result.Rows))
+		}
+		hostID, err := r.Result.Rows[0][0].AsUUID()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hostID != r.Host.HostID {
+			t.Fatalf("query run on host %v, expected %v", hostID, r.Host.HostID)
+		}
+	}
+}
+
+type recordingQueryObserver struct {
+	mu    sync.Mutex
+	start []QueryEvent
+	end   []QueryEndEvent
+}
+
+func (o *recordingQueryObserver) OnQueryStart(ev QueryEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.start = append(o.start, ev)
+}
+
+func (o *recordingQueryObserver) OnQueryEnd(ev QueryEndEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.end = append(o.end, ev)
+}
+
+func TestSessionQueryObserverIntegration(t *testing.T) { // nolint:paralleltest // Integration tests are not run in parallel!
+	defer goleak.VerifyNone(t)
+	initKeyspace(t)
+
+	obs := new(recordingQueryObserver)
+	cfg := testingSessionConfig
+	cfg.QueryObserver = obs
+	session, err := NewSession(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close(context.Background())
+
+	const stmt = "SELECT host_id FROM system.local"
+	q := session.Query(stmt)
+	if _, err := q.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	q.AsyncExec()
+	if _, err := q.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+
+	const pagedStmt = "SELECT keyspace_name FROM system_schema.keyspaces"
+	p := session.Query(pagedStmt)
+	p.SetPageSize(1)
+	it := p.Iter()
+	for _, err = it.Next(); err == nil; _, err = it.Next() {
+	}
+	if !errors.Is(err, ErrNoMoreRows) {
+		t.Fatal(err)
+	}
+
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	if len(obs.start) < 4 || len(obs.start) != len(obs.end) {
+		t.Fatalf("got %d start and %d end events, expected at least 4", len(obs.start), len(obs.end))
+	}
+	for _, ev := range obs.end[:2] {
+		if ev.Statement != stmt || ev.Attempt != 1 || ev.Page != 1 || ev.Rows != 1 || ev.Err != nil || ev.Host.Addr == "" {
+			t.Fatalf("unexpected event %+v", ev)
+		}
+	}
+	for i, ev := range obs.end[2:] {
+		if ev.Statement != pagedStmt || ev.Attempt != 1 || ev.Page != i+1 || ev.Err != nil {
+			t.Fatalf("unexpected page event %+v", ev)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package scylla
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/internal/testutil"
+	"github.com/mmatczuk/scylla-go-driver/transport"
+	"go.uber.org/goleak"
+)
+
+// fakeNodes returns n nodes of a single datacenter with addresses starting at 10.0.0.1.
+func fakeNodes(n int) []testutil.Node {
+	nodes := make([]testutil.Node, n)
+	for i := range nodes {
+		nodes[i] = testutil.Node{
+			HostID: frame.UUID{byte(i + 1)},
+			Addr:   net.IPv4(10, 0, 0, byte(i+1)),
+			DC:     "dc1",
+			Rack:   "rack1",
+		}
+	}
+	return nodes
+}
+
+// fakeTokenNodes returns n nodes of fakeNodes with a single token each, tokens are spread evenly over the ring.
+func fakeTokenNodes(n int) []testutil.Node {
+	nodes := fakeNodes(n)
+	step := math.MaxInt64 / int64(n) * 2
+	for i := range nodes {
+		nodes[i].Tokens = []string{strconv.FormatInt(math.MinInt64+int64(i)*step, 10)}
+	}
+	return nodes
+}
+
+// fakeSession returns session connected to fake cluster c.
+func fakeSession(t *testing.T, c *testutil.Cluster, cfg SessionConfig) *Session {
+	t.Helper()
+
+	cfg.Hosts = []string{c.Nodes[0].Addr.String()}
+	cfg.Dialer = transport.DialerFunc(c.Dial)
+	s, err := NewSession(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// blockingQuery returns query handler which blocks queries containing "blocking" until release is closed,
+// received gets a value for every blocked query.
+func blockingQuery(received chan<- struct{}, release <-chan struct{}) testutil.QueryHandler {
+	return func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+		if bytes.Contains(body, []byte("blocking")) {
+			received <- struct{}{}
+			<-release
+		}
+		return frame.OpResult, testutil.VoidResult()
+	}
+}
+
+func TestSessionCloseWaitsForInFlightRequests(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	received := make(chan struct{}, 1)
+	release := make(chan struct{})
+	c := &testutil.Cluster{Nodes: fakeNodes(1), Query: blockingQuery(received, release)}
+	s := fakeSession(t, c, DefaultSessionConfig(""))
+
+	execErr := make(chan error, 1)
+	go func() {
+		q := s.Query("SELECT blocking")
+		_, err := q.Exec()
+		execErr <- err
+	}()
+	<-received
+	q := s.Query("SELECT v FROM ks.t")
+	it := q.Iter()
+
+	closeErr := make(chan error, 1)
+	go func() {
+		closeErr <- s.Close(context.Background())
+	}()
+	select {
+	case <-closeErr:
+		t.Fatal("session closed with request in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := q.Exec(); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("got error %v, expected %v", err, ErrSessionClosed)
+	}
+
+	close(release)
+	if err := <-execErr; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-closeErr:
+		t.Fatal("session closed with iter open")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	it.Close()
+	if err := <-closeErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSessionCloseTimeout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	c := &testutil.Cluster{Nodes: fakeNodes(1)}
+	s := fakeSession(t, c, DefaultSessionConfig(""))
+
+	q := s.Query("SELECT v FROM ks.t")
+	it := q.Iter()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if _, err := it.Next(); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("got error %v, expected %v", err, ErrSessionClosed)
+	}
+}
+
+// breakerRecorder records node circuit breaker state changes.
+type breakerRecorder struct {
+	transport.LoggingConnObserver
+	states chan transport.BreakerState
+}
+
+func (r breakerRecorder) OnBreakerStateChange(ev transport.BreakerEvent) {
+	r.states <- ev.To
+}
+
+func TestIterReportsResultToBreaker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	c := &testutil.Cluster{
+		Nodes: fakeNodes(1),
+		Query: func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+			return testutil.ErrorResponse(frame.ErrCodeOverloaded, "overloaded")
+		},
+	}
+	r := breakerRecorder{
+		LoggingConnObserver: transport.LoggingConnObserver{Logger: NopLogger{}},
+		states:              make(chan transport.BreakerState, 10),
+	}
+	cfg := DefaultSessionConfig("")
+	cfg.ConnObserver = r
+	cfg.CircuitBreaker = transport.CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute}
+	s := fakeSession(t, c, cfg)
+	defer s.Close(context.Background())
+
+	q := s.Query("SELECT v FROM ks.t")
+	it := q.Iter()
+	if _, err := it.Next(); err == nil {
+		t.Fatal("expected error")
+	}
+	select {
+	case v := <-r.states:
+		if v != transport.BreakerOpen {
+			t.Fatalf("got breaker state %v, expected %v", v, transport.BreakerOpen)
+		}
+	default:
+		t.Fatal("breaker not opened")
+	}
+}
+
+func TestQuerySetHostEmptyRestoresPolicy(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	c := &testutil.Cluster{Nodes: fakeNodes(2)}
+	s := fakeSession(t, c, DefaultSessionConfig(""))
+	defer s.Close(context.Background())
+
+	q := s.Query("SELECT v FROM ks.t")
+	q.SetHost(c.Nodes[1].Addr.String()).SetShard(0)
+	if _, err := q.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	q.SetHost("")
+	if _, err := q.Exec(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQuerySetRoutingKeyMatchesPreparedToken(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	executed := make(chan string, 2)
+	c := &testutil.Cluster{
+		Nodes:     fakeTokenNodes(3),
+		Keyspaces: map[string]map[string]string{"ks": {"class": "SimpleStrategy", "replication_factor": "1"}},
+		Query: func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+			switch {
+			case h.OpCode == frame.OpPrepare:
+				return frame.OpResult, testutil.PreparedResult([]byte("id"), 3, 0, 1)
+			case h.OpCode == frame.OpExecute, bytes.Contains(body, []byte("INSERT")):
+				executed <- addr
+			}
+			return frame.OpResult, testutil.VoidResult()
+		},
+	}
+	s := fakeSession(t, c, DefaultSessionConfig("ks"))
+	defer s.Close(context.Background())
+
+	for _, v := range [][2]int64{{1, 2}, {-7, 1 << 40}, {42, 0}} {
+		prepared, err := s.Prepare("INSERT INTO ks.t (pk1, pk2, v) VALUES (?, ?, ?)")
+		if err != nil {
+			t.Fatal(err)
+		}
+		prepared.BindInt64(0, v[0]).BindInt64(1, v[1]).BindInt64(2, 0)
+
+		var k1, k2 frame.Buffer
+		k1.WriteLong(v[0])
+		k2.WriteLong(v[1])
+		routed := s.Query("INSERT INTO ks.t (pk1, pk2, v) VALUES (1, 2, 0)")
+		routed.SetRoutingKey(k1.Bytes(), k2.Bytes())
+
+		expected, _ := prepared.token()
+		if got, _ := routed.token(); got != expected {
+			t.Fatalf("values %v: got token %d, expected %d", v, got, expected)
+		}
+
+		if _, err := prepared.Exec(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := routed.Exec(); err != nil {
+			t.Fatal(err)
+		}
+		if p, r := <-executed, <-executed; p != r {
+			t.Fatalf("values %v: prepared statement sent to %s, query with routing key to %s", v, p, r)
+		}
+	}
+}
+
+func TestSessionBroadcast(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	received := make(chan string, 3)
+	c := &testutil.Cluster{
+		Nodes: fakeNodes(3),
+		Query: func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+			if !bytes.Contains(body, []byte("TRUNCATE")) {
+				return frame.OpResult, testutil.VoidResult()
+			}
+			received <- addr
+			if addr == "10.0.0.2" {
+				return testutil.ErrorResponse(frame.ErrCodeOverloaded, "overloaded")
+			}
+			return frame.OpResult, testutil.VoidResult()
+		},
+	}
+	s := fakeSession(t, c, DefaultSessionConfig(""))
+	defer s.Close(context.Background())
+
+	res := s.Broadcast(s.Query("TRUNCATE ks.t"))
+	hosts := s.Hosts()
+	if len(res) != len(hosts) {
+		t.Fatalf("got %d results, expected %d", len(res), len(hosts))
+	}
+	for i, r := range res {
+		if r.Host.Addr != hosts[i].Addr {
+			t.Fatalf("result %d: got host %s, expected %s", i, r.Host.Addr, hosts[i].Addr)
+		}
+		if failed := r.Host.Addr == "10.0.0.2"; failed != (r.Err != nil) {
+			t.Fatalf("host %s: unexpected error %v", r.Host.Addr, r.Err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for range hosts {
+		seen[<-received] = true
+	}
+	for _, h := range hosts {
+		if !seen[h.Addr] {
+			t.Fatalf("host %s did not receive the query", h.Addr)
+		}
+	}
+}
+
+// queryRecorder records ends of request attempts.
+type queryRecorder struct {
+	ends chan QueryEndEvent
+}
+
+func (r queryRecorder) OnQueryStart(ev QueryEvent) {}
+
+func (r queryRecorder) OnQueryEnd(ev QueryEndEvent) {
+	r.ends <- ev
+}
+
+func TestQueryObserver(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	c := &testutil.Cluster{
+		Nodes: fakeNodes(1),
+		Query: func(addr string, h frame.Header, body []byte) (frame.OpCode, []byte) {
+			switch {
+			case bytes.Contains(body, []byte("fail")):
+				return testutil.ErrorResponse(frame.ErrCodeInvalid, "invalid")
+			case bytes.Contains(body, []byte("page2")):
+				return frame.OpResult, testutil.RowsResult(nil, "c")
+			default:
+				return frame.OpResult, testutil.RowsResult([]byte("page2"), "a", "b")
+			}
+		},
+	}
+	r := queryRecorder{ends: make(chan QueryEndEvent, 10)}
+	cfg := DefaultSessionConfig("")
+	cfg.QueryObserver = r
+	s := fakeSession(t, c, cfg)
+	defer s.Close(context.Background())
+
+	type end struct {
+		page int
+		rows int
+		err  bool
+	}
+	expect := func(name string, expected ...end) {
+		t.Helper()
+		for _, e := range expected {
+			var ev QueryEndEvent
+			select {
+			case ev = <-r.ends:
+			default:
+				t.Fatalf("%s: missing query end event %+v", name, e)
+			}
+			if got := (end{page: ev.Page, rows: ev.Rows, err: ev.Err != nil}); got != e {
+				t.Fatalf("%s: got %+v, expected %+v", name, got, e)
+			}
+			if ev.Attempt != 1 || ev.Host.Addr != "10.0.0.1" || ev.Statement == "" {
+				t.Fatalf("%s: unexpected event %+v", name, ev)
+			}
+		}
+		select {
+		case ev := <-r.ends:
+			t.Fatalf("%s: unexpected event %+v", name, ev)
+		default:
+		}
+	}
+
+	q := s.Query("SELECT v FROM ks.t")
+	if _, err := q.Exec(); err != nil {
+		t.Fatal(err)
+	}
+	expect("exec", end{page: 1, rows: 2})
+
+	fail := s.Query("SELECT fail FROM ks.t")
+	if _, err := fail.Exec(); err == nil {
+		t.Fatal("expected error")
+	}
+	expect("exec error", end{page: 1, err: true})
+
+	q.AsyncExec()
+	fail.AsyncExec()
+	if _, err := q.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fail.Fetch(); err == nil {
+		t.Fatal("expected error")
+	}
+	expect("fetch", end{page: 1, rows: 2}, end{page: 1, err: true})
+
+	it := q.Iter()
+	for {
+		if _, err := it.Next(); err != nil {
+			if !errors.Is(err, ErrNoMoreRows) {
+				t.Fatal(err)
+			}
+			break
+		}
+	}
+	it.Close()
+	expect("iter", end{page: 1, rows: 2}, end{page: 2, rows: 1})
+
+	it = fail.Iter()
+	if _, err := it.Next(); err == nil {
+		t.Fatal("expected error")
+	}
+	it.Close()
+	expect("iter error", end{page: 1, err: true})
+}
@@ -0,0 +1,16 @@
+//go:build go1.21
+
+package scylla
+
+import (
+	"log/slog"
+
+	"github.com/mmatczuk/scylla-go-driver/transport"
+)
+
+type SlogLogger = transport.SlogLogger
+
+// NewSlogLogger returns Logger writing to l, if l is nil slog.Default is used.
+func NewSlogLogger(l *slog.Logger) SlogLogger {
+	return transport.NewSlogLogger(l)
+}
@@ -0,0 +1,236 @@
+package transport
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// AdmissionConfig configures client-side admission control.
+// Admission control is disabled if neither MaxInFlight nor MaxInFlightPerNode is set.
+type AdmissionConfig struct {
+	// MaxInFlight limits number of requests in flight in the whole session, zero means no limit.
+	MaxInFlight int
+	// MaxInFlightPerNode limits number of requests in flight to a single node, zero means no limit.
+	MaxInFlightPerNode int
+	// MaxQueued limits number of requests waiting for admission,
+	// requests exceeding the limit are rejected immediately.
+	// Zero disables queueing, requests over the limits are rejected immediately then.
+	MaxQueued int
+	// MaxWait is the maximal time a request waits for admission.
+	// Zero rejects queued requests immediately unless a slot is released at the same time.
+	MaxWait time.Duration
+}
+
+func (cfg AdmissionConfig) enabled() bool {
+	return cfg.MaxInFlight > 0 || cfg.MaxInFlightPerNode > 0
+}
+
+var ErrOverloaded = errors.New("client overloaded")
+
+// OverloadedError is returned when request is rejected by admission control,
+// errors.Is(err, ErrOverloaded) reports true for it.
+type OverloadedError struct {
+	Addr     string // Empty if session-wide limit was exceeded.
+	InFlight int
+	Queued   int
+	Waited   time.Duration
+}
+
+func (e OverloadedError) Error() string {
+	if e.Addr == "" {
+		return fmt.Sprintf("%s: %d requests in flight, %d queued, waited %s", ErrOverloaded, e.InFlight, e.Queued, e.Waited)
+	}
+	return fmt.Sprintf("%s: node %s has %d requests in flight, %d queued, waited %s", ErrOverloaded, e.Addr, e.InFlight, e.Queued, e.Waited)
+}
+
+func (e OverloadedError) Is(target error) bool {
+	return target == ErrOverloaded
+}
+
+// AdmissionController limits the number of requests in flight, a request reserves a slot with Admit
+// and frees it with Release once it completes. Requests over the limits wait in a bounded FIFO queue
+// and are woken up when slots are released.
+//
+// It keeps its own counters instead of using Conn.Stats, requests are admitted before a connection
+// is picked and connection counters are increased only after the request is queued on the connection,
+// so concurrent requests checking them could all exceed the limit. Nodes removed from topology
+// are forgotten with RemoveNode.
+type AdmissionController struct {
+	cfg AdmissionConfig
+
+	inFlight     int
+	nodeInFlight map[string]int // node address to number of reserved slots
+	queue        list.List      // *admissionWaiter
+	mu           sync.Mutex     // mu guards inFlight, nodeInFlight and queue
+}
+
+type admissionWaiter struct {
+	node  *Node
+	ready chan struct{} // closed when slot is reserved for the waiter
+}
+
+// NewAdmissionController returns nil if cfg does not set any limits.
+func NewAdmissionController(cfg AdmissionConfig) *AdmissionController {
+	if !cfg.enabled() {
+		return nil
+	}
+	return &AdmissionController{
+		cfg:          cfg,
+		nodeInFlight: make(map[string]int),
+	}
+}
+
+// Admit reserves a slot for a request to n, if there is no free slot it waits in queue until one
+// is released. It returns OverloadedError if the queue is full or request waited longer than MaxWait.
+// Every successful Admit must be followed by Release.
+func (a *AdmissionController) Admit(n *Node) error {
+	a.mu.Lock()
+	// Queued requests are never admissible, wake admits them as soon as they are,
+	// so request that fits in the limits does not jump the queue.
+	if a.admissible(n) {
+		a.reserve(n)
+		a.mu.Unlock()
+		return nil
+	}
+	if a.queue.Len() >= a.cfg.MaxQueued {
+		err := a.overloaded(n, 0)
+		a.mu.Unlock()
+		return err
+	}
+	w := &admissionWaiter{node: n, ready: make(chan struct{})}
+	e := a.queue.PushBack(w)
+	a.mu.Unlock()
+
+	start := Now()
+	timer := time.NewTimer(a.cfg.MaxWait)
+	defer timer.Stop()
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	select {
+	case <-w.ready:
+		// Slot was reserved while timer fired.
+		return nil
+	default:
+	}
+	a.queue.Remove(e)
+	return a.overloaded(n, Now().Sub(start))
+}
+
+// Release frees slot reserved by Admit and admits queued requests which fit in the limits.
+func (a *AdmissionController) Release(n *Node) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inFlight--
+	// Node may have been removed with RemoveNode while request was in flight.
+	if v, ok := a.nodeInFlight[n.addr]; ok {
+		if v > 1 {
+			a.nodeInFlight[n.addr] = v - 1
+		} else {
+			delete(a.nodeInFlight, n.addr)
+		}
+	}
+	a.wake()
+}
+
+// RemoveNode forgets slots reserved for requests to node with addr, it's called when the node
+// is removed from topology. Requests in flight still hold their session-wide slots until Release.
+func (a *AdmissionController) RemoveNode(addr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.nodeInFlight, addr)
+	a.wake()
+}
+
+// HostListener returns listener which removes nodes from a when they are removed from topology
+// and passes all notifications to l, l may be nil.
+func (a *AdmissionController) HostListener(l HostListener) HostListener {
+	return admissionHostListener{a: a, l: l}
+}
+
+type admissionHostListener struct {
+	a *AdmissionController
+	l HostListener
+}
+
+func (h admissionHostListener) OnAdd(v HostInfo) {
+	if h.l != nil {
+		h.l.OnAdd(v)
+	}
+}
+
+func (h admissionHostListener) OnRemove(v HostInfo) {
+	h.a.RemoveNode(v.Addr)
+	if h.l != nil {
+		h.l.OnRemove(v)
+	}
+}
+
+func (h admissionHostListener) OnUp(v HostInfo) {
+	if h.l != nil {
+		h.l.OnUp(v)
+	}
+}
+
+func (h admissionHostListener) OnDown(v HostInfo) {
+	if h.l != nil {
+		h.l.OnDown(v)
+	}
+}
+
+// wake reserves slots for queued requests in order, requests to nodes at their limit
+// keep their place in the queue. Caller must hold a.mu.
+func (a *AdmissionController) wake() {
+	for e := a.queue.Front(); e != nil; {
+		if a.cfg.MaxInFlight > 0 && a.inFlight >= a.cfg.MaxInFlight {
+			return
+		}
+		next := e.Next()
+		w := e.Value.(*admissionWaiter)
+		if a.admissible(w.node) {
+			a.reserve(w.node)
+			a.queue.Remove(e)
+			close(w.ready)
+		}
+		e = next
+	}
+}
+
+func (a *AdmissionController) admissible(n *Node) bool {
+	if a.cfg.MaxInFlightPerNode > 0 && a.nodeInFlight[n.addr] >= a.cfg.MaxInFlightPerNode {
+		return false
+	}
+	if a.cfg.MaxInFlight > 0 && a.inFlight >= a.cfg.MaxInFlight {
+		return false
+	}
+	return true
+}
+
+func (a *AdmissionController) reserve(n *Node) {
+	a.inFlight++
+	a.nodeInFlight[n.addr]++
+}
+
+func (a *AdmissionController) overloaded(n *Node, waited time.Duration) error {
+	err := OverloadedError{
+		Queued: a.queue.Len(),
+		Waited: waited,
+	}
+	if v := a.nodeInFlight[n.addr]; a.cfg.MaxInFlightPerNode > 0 && v >= a.cfg.MaxInFlightPerNode {
+		err.Addr = n.addr
+		err.InFlight = v
+	} else {
+		err.InFlight = a.inFlight
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+package transport
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+// mockAdmission returns controller with given number of requests admitted to each of the returned nodes.
+func mockAdmission(t *testing.T, cfg AdmissionConfig, inFlight ...int) (*AdmissionController, []*Node) {
+	t.Helper()
+
+	a := NewAdmissionController(cfg)
+	nodes := make([]*Node, len(inFlight))
+	for i, v := range inFlight {
+		nodes[i] = &Node{addr: strconv.Itoa(i)}
+		for j := 0; j < v; j++ {
+			a.reserve(nodes[i])
+		}
+	}
+	return a, nodes
+}
+
+func TestAdmissionController(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		cfg      AdmissionConfig
+		waiting  []int
+		node     int
+		admitted bool
+		addr     string
+	}{
+		{
+			name:     "under limits",
+			cfg:      AdmissionConfig{MaxInFlight: 10, MaxInFlightPerNode: 5},
+			waiting:  []int{4, 4},
+			admitted: true,
+		},
+		{
+			name:    "node limit exceeded",
+			cfg:     AdmissionConfig{MaxInFlight: 10, MaxInFlightPerNode: 5},
+			waiting: []int{5, 0},
+			addr:    "0",
+		},
+		{
+			name:     "other node limit exceeded",
+			cfg:      AdmissionConfig{MaxInFlight: 10, MaxInFlightPerNode: 5},
+			waiting:  []int{5, 0},
+			node:     1,
+			admitted: true,
+		},
+		{
+			name:    "session limit exceeded",
+			cfg:     AdmissionConfig{MaxInFlight: 10, MaxInFlightPerNode: 5},
+			waiting: []int{4, 3, 3},
+		},
+		{
+			name:    "wait timeout",
+			cfg:     AdmissionConfig{MaxInFlight: 10, MaxQueued: 1, MaxWait: 5 * time.Millisecond},
+			waiting: []int{10},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, nodes := mockAdmission(t, tc.cfg, tc.waiting...)
+			err := a.Admit(nodes[tc.node])
+			if tc.admitted {
+				if err != nil {
+					t.Fatalf("expected request to be admitted, got %s", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrOverloaded) {
+				t.Fatalf("expected overloaded error, got %v", err)
+			}
+			var v OverloadedError
+			if !errors.As(err, &v) || v.Addr != tc.addr {
+				t.Fatalf("expected overloaded error for node %q, got %v", tc.addr, err)
+			}
+		})
+	}
+}
+
+func TestAdmissionControllerQueue(t *testing.T) {
+	t.Parallel()
+
+	a, nodes := mockAdmission(t, AdmissionConfig{MaxInFlightPerNode: 1, MaxQueued: 2, MaxWait: time.Second}, 1, 1)
+	first := make(chan error)
+	go func() {
+		first <- a.Admit(nodes[0])
+	}()
+	waitQueued(a, 1)
+	second := make(chan error)
+	go func() {
+		second <- a.Admit(nodes[0])
+	}()
+	waitQueued(a, 2)
+
+	// Queue is full, the next request should be rejected right away.
+	if err := a.Admit(nodes[1]); !errors.Is(err, ErrOverloaded) {
+		t.Fatalf("expected overloaded error, got %v", err)
+	}
+
+	// Request to other node should not wake up waiters.
+	a.Release(nodes[1])
+	select {
+	case err := <-first:
+		t.Fatalf("expected request to wait, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Requests should be admitted in order.
+	a.Release(nodes[0])
+	if err := <-first; err != nil {
+		t.Fatalf("expected queued request to be admitted, got %s", err)
+	}
+	select {
+	case err := <-second:
+		t.Fatalf("expected request to wait, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	a.Release(nodes[0])
+	if err := <-second; err != nil {
+		t.Fatalf("expected queued request to be admitted, got %s", err)
+	}
+}
+
+func TestAdmissionControllerRemoveNode(t *testing.T) {
+	t.Parallel()
+
+	a, nodes := mockAdmission(t, AdmissionConfig{MaxInFlight: 3, MaxInFlightPerNode: 2, MaxQueued: 1, MaxWait: time.Second}, 2)
+	queued := make(chan error)
+	go func() {
+		queued <- a.Admit(nodes[0])
+	}()
+	waitQueued(a, 1)
+
+	// Removed node is forgotten, queued request fits in the session-wide limit.
+	a.HostListener(nil).OnRemove(HostInfo{Addr: nodes[0].addr})
+	if err := <-queued; err != nil {
+		t.Fatalf("expected queued request to be admitted, got %s", err)
+	}
+	if v := a.nodeInFlight[nodes[0].addr]; v != 1 {
+		t.Fatalf("got %d requests in flight to removed node, expected 1", v)
+	}
+
+	// Requests sent before removal still hold session-wide slots.
+	other := &Node{addr: "other"}
+	if a.admissible(other) {
+		t.Fatal("expected session-wide limit to be exceeded")
+	}
+	for i := 0; i < 3; i++ {
+		a.Release(nodes[0])
+	}
+	if a.inFlight != 0 || len(a.nodeInFlight) != 0 {
+		t.Fatalf("got %d requests in flight and node counters %v, expected none", a.inFlight, a.nodeInFlight)
+	}
+}
+
+func TestAdmissionControllerBurst(t *testing.T) {
+	t.Parallel()
+
+	const limit = 10
+	a, nodes := mockAdmission(t, AdmissionConfig{MaxInFlight: limit}, 0)
+
+	var (
+		wg       sync.WaitGroup
+		admitted atomic.Int32
+	)
+	for i := 0; i < 10*limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if a.Admit(nodes[0]) == nil {
+				admitted.Inc()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v := admitted.Load(); v != limit {
+		t.Fatalf("expected %d requests to be admitted, got %d", limit, v)
+	}
+}
+
+func waitQueued(a *AdmissionController, n int) {
+	for {
+		a.mu.Lock()
+		v := a.queue.Len()
+		a.mu.Unlock()
+		if v == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAdmissionControllerDisabled(t *testing.T) {
+	t.Parallel()
+
+	if a := NewAdmissionController(AdmissionConfig{MaxQueued: 10}); a != nil {
+		t.Fatalf("expected admission control to be disabled")
+	}
+}
@@ -0,0 +1,88 @@
+package transport
+
+import (
+	"fmt"
+)
+
+// Authenticator performs SASL authentication of a single connection.
+type Authenticator interface {
+	// InitialResponse returns the first token sent to the server.
+	InitialResponse() ([]byte, error)
+	// Challenge returns response to the server challenge token.
+	Challenge(token []byte) ([]byte, error)
+	// Success is called when the server accepts authentication, token may be nil.
+	Success(token []byte) error
+}
+
+// AuthProvider creates Authenticator for connection to addr, name is the class name of the server authenticator.
+// It's called concurrently for every new connection that requires authentication.
+type AuthProvider interface {
+	NewAuthenticator(addr, name string) (Authenticator, error)
+}
+
+// AuthProviderFunc is an adapter allowing to use a function as AuthProvider.
+type AuthProviderFunc func(addr, name string) (Authenticator, error)
+
+func (f AuthProviderFunc) NewAuthenticator(addr, name string) (Authenticator, error) {
+	return f(addr, name)
+}
+
+// 'AllowAllAuthenticator' and 'org.apache.cassandra.auth.AllowAllAuthenticator' do not require authentication.
+var approvedAuthenticators = map[string]struct{}{
+	"PasswordAuthenticator":                           {},
+	"org.apache.cassandra.auth.PasswordAuthenticator": {},
+	"com.scylladb.auth.TransitionalAuthenticator":     {},
+	"com.scylladb.auth.SaslauthdAuthenticator":        {},
+}
+
+// PasswordAuthenticator is AuthProvider for servers accepting SASL PLAIN username and password,
+// it's used if ConnConfig.AuthProvider is not set.
+type PasswordAuthenticator struct {
+	Username string
+	Password string
+}
+
+var (
+	_ AuthProvider  = PasswordAuthenticator{}
+	_ Authenticator = PasswordAuthenticator{}
+)
+
+func (a PasswordAuthenticator) NewAuthenticator(_, name string) (Authenticator, error) {
+	if _, ok := approvedAuthenticators[name]; !ok {
+		return nil, fmt.Errorf("authenticator %q not supported", name)
+	}
+	return a, nil
+}
+
+func (a PasswordAuthenticator) InitialResponse() ([]byte, error) {
+	return []byte("\x00" + a.Username + "\x00" + a.Password), nil
+}
+
+func (a PasswordAuthenticator) Challenge(token []byte) ([]byte, error) {
+	return nil, fmt.Errorf("unexpected authentication challenge %q", token)
+}
+
+func (a PasswordAuthenticator) Success(token []byte) error {
+	return nil
+}
+
+// authProvider returns AuthProvider from config or PasswordAuthenticator with credentials
+// from CredentialsProvider or Username and Password.
+func (cfg ConnConfig) authProvider() AuthProvider {
+	if cfg.AuthProvider != nil {
+		return cfg.AuthProvider
+	}
+	if p := cfg.CredentialsProvider; p != nil {
+		return AuthProviderFunc(func(addr, name string) (Authenticator, error) {
+			username, password, err := p()
+			if err != nil {
+				return nil, fmt.Errorf("get credentials: %w", err)
+			}
+			return PasswordAuthenticator{Username: username, Password: password}.NewAuthenticator(addr, name)
+		})
+	}
+	return PasswordAuthenticator{
+		Username: cfg.Username,
+		Password: cfg.Password,
+	}
+}
@@ -0,0 +1,132 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+type challengeAuthenticator struct {
+	success []byte
+}
+
+func (a *challengeAuthenticator) InitialResponse() ([]byte, error) {
+	return []byte("hello"), nil
+}
+
+func (a *challengeAuthenticator) Challenge(token []byte) ([]byte, error) {
+	return append([]byte("signed:"), token...), nil
+}
+
+func (a *challengeAuthenticator) Success(token []byte) error {
+	a.success = token
+	return nil
+}
+
+// fakeAuthServer requests authentication with authenticator name and expects responses in order,
+// it sends challenge after each response except the last one which is answered with success.
+func fakeAuthServer(name string, expected ...string) (fakeHandler, *[]string) {
+	var got []string
+	return func(h frame.Header, body []byte) (frame.OpCode, []byte) {
+		if op, res := fakeSupported(h); op != 0 {
+			return op, res
+		}
+
+		var b frame.Buffer
+		switch h.OpCode {
+		case frame.OpStartup:
+			b.WriteString(name)
+			return frame.OpAuthenticate, b.Bytes()
+		case frame.OpAuthResponse:
+			var in frame.Buffer
+			in.Write(body)
+			token := string(in.ReadBytes())
+			got = append(got, token)
+			if len(got) >= len(expected) || token != expected[len(got)-1] {
+				b.WriteBytes(frame.Bytes("done"))
+				return frame.OpAuthSuccess, b.Bytes()
+			}
+			b.WriteBytes(frame.Bytes(fmt.Sprintf("nonce%d", len(got))))
+			return frame.OpAuthChallenge, b.Bytes()
+		default:
+			panic(fmt.Sprintf("unexpected op code %v", h.OpCode))
+		}
+	}, &got
+}
+
+func TestConnAuthChallenge(t *testing.T) {
+	t.Parallel()
+
+	h, got := fakeAuthServer("com.example.CustomAuthenticator", "hello", "signed:nonce1", "signed:nonce2")
+	auth := new(challengeAuthenticator)
+	var gotName string
+
+	cfg := DefaultConnConfig("")
+	cfg.AuthProvider = AuthProviderFunc(func(addr, name string) (Authenticator, error) {
+		gotName = name
+		return auth, nil
+	})
+
+	conn, err := WrapConn(newFakeConn(t, h), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if gotName != "com.example.CustomAuthenticator" {
+		t.Fatalf("got authenticator name %q", gotName)
+	}
+	if v := strings.Join(*got, ","); v != "hello,signed:nonce1,signed:nonce2" {
+		t.Fatalf("got responses %s", v)
+	}
+	if !bytes.Equal(auth.success, []byte("done")) {
+		t.Fatalf("got success token %q", auth.success)
+	}
+}
+
+func TestConnPasswordAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	t.Run("approved", func(t *testing.T) {
+		t.Parallel()
+
+		h, got := fakeAuthServer("org.apache.cassandra.auth.PasswordAuthenticator", "\x00user\x00pass")
+		cfg := DefaultConnConfig("")
+		cfg.Username = "user"
+		cfg.Password = "pass"
+
+		conn, err := WrapConn(newFakeConn(t, h), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if len(*got) != 1 {
+			t.Fatalf("got responses %q", *got)
+		}
+	})
+
+	t.Run("not supported", func(t *testing.T) {
+		t.Parallel()
+
+		h, _ := fakeAuthServer("com.example.CustomAuthenticator")
+		conn, err := WrapConn(newFakeConn(t, h), DefaultConnConfig(""))
+		if conn != nil {
+			defer conn.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), "not supported") {
+			t.Fatalf("got error %v, expected not supported error", err)
+		}
+	})
+
+	t.Run("challenge", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := (PasswordAuthenticator{}).Challenge([]byte("x")); err == nil {
+			t.Fatal("expected error")
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+
+	"go.uber.org/atomic"
+)
+
+type BreakerState int32
+
+const (
+	// BreakerClosed lets all requests through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects requests until OpenTimeout passes.
+	BreakerOpen
+	// BreakerHalfOpen lets requests through, the first result decides if breaker closes or opens again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int32(s))
+	}
+}
+
+// CircuitBreakerConfig configures per node circuit breaker, zero FailureThreshold disables it.
+// Breaker is disabled by default.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker, failures are
+	// connection errors and timeouts, and overloaded or bootstrapping errors returned by the node.
+	FailureThreshold int
+	// OpenTimeout is the time after which open breaker becomes half-open.
+	OpenTimeout time.Duration
+}
+
+// circuitBreaker keeps nodes that keep failing out of query plans.
+type circuitBreaker struct {
+	cfg      CircuitBreakerConfig
+	addr     string
+	obs      BreakerObserver
+	state    atomic.Int32 // BreakerState, allows reading state without locking mu
+	failures atomic.Int32 // consecutive failures, allows reading failures without locking mu
+	openedAt time.Time
+	mu       sync.Mutex // mu guards state and failures changes and openedAt
+}
+
+func newCircuitBreaker(addr string, cfg CircuitBreakerConfig, connObs ConnObserver) *circuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		return nil
+	}
+	obs, _ := connObs.(BreakerObserver)
+	return &circuitBreaker{
+		cfg:  cfg,
+		addr: addr,
+		obs:  obs,
+	}
+}
+
+func (b *circuitBreaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	return BreakerState(b.state.Load())
+}
+
+// allow returns false if breaker is open, breaker becomes half-open once OpenTimeout passes.
+func (b *circuitBreaker) allow() bool {
+	if b == nil || BreakerState(b.state.Load()) != BreakerOpen {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if BreakerState(b.state.Load()) == BreakerOpen && Now().Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(BreakerHalfOpen)
+	}
+	return BreakerState(b.state.Load()) != BreakerOpen
+}
+
+func (b *circuitBreaker) success() {
+	if b == nil || BreakerState(b.state.Load()) == BreakerClosed && b.failures.Load() == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures.Store(0)
+	if BreakerState(b.state.Load()) != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+func (b *circuitBreaker) failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failures := int(b.failures.Inc())
+	switch BreakerState(b.state.Load()) {
+	case BreakerHalfOpen:
+		b.openedAt = Now()
+		b.setState(BreakerOpen)
+	case BreakerClosed:
+		if failures >= b.cfg.FailureThreshold {
+			b.openedAt = Now()
+			b.setState(BreakerOpen)
+		}
+	case BreakerOpen:
+	}
+}
+
+// setState must be called with mu held.
+func (b *circuitBreaker) setState(s BreakerState) {
+	from := BreakerState(b.state.Swap(int32(s)))
+	if b.obs != nil && from != s {
+		b.obs.OnBreakerStateChange(BreakerEvent{
+			Addr: b.addr,
+			From: from,
+			To:   s,
+		})
+	}
+}
+
+// isNodeFailure returns true if err indicates problems with the node rather than with the query,
+// that is if the node can't be reached, I/O on the connection fails or times out, or the node
+// reports that it's overloaded or bootstrapping. Unavailable, read timeout and write timeout errors
+// are not counted, a healthy coordinator returns them when other replicas are down or slow.
+func isNodeFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var v CodedError
+	if errors.As(err, &v) {
+		switch v.ErrorCode() {
+		case frame.ErrCodeOverloaded, frame.ErrCodeBootstrapping:
+			return true
+		default:
+			return false
+		}
+	}
+	return isTransportError(err)
+}
+
+// isTransportError returns true if err comes from dialing or from I/O on a connection
+// which was not closed by the driver.
+func isTransportError(err error) bool {
+	if err == nil || errors.Is(err, net.ErrClosed) {
+		return false
+	}
+	var v net.Error
+	return errors.As(err, &v) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
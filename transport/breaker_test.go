@@ -0,0 +1,110 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+)
+
+type recordingBreakerObserver struct {
+	LoggingConnObserver
+	events []string
+}
+
+func (o *recordingBreakerObserver) OnBreakerStateChange(ev BreakerEvent) {
+	o.events = append(o.events, ev.To.String())
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	const openTimeout = 10 * time.Millisecond
+	obs := &recordingBreakerObserver{}
+	b := newCircuitBreaker("1", CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: openTimeout}, obs)
+
+	b.failure()
+	b.failure()
+	b.success()
+	b.failure()
+	b.failure()
+	if !b.allow() || b.State() != BreakerClosed {
+		t.Fatalf("breaker should be closed as failures were not consecutive, got %s", b.State())
+	}
+
+	b.failure()
+	if b.allow() || b.State() != BreakerOpen {
+		t.Fatalf("breaker should be open, got %s", b.State())
+	}
+
+	time.Sleep(openTimeout)
+	if !b.allow() || b.State() != BreakerHalfOpen {
+		t.Fatalf("breaker should be half-open, got %s", b.State())
+	}
+	b.failure()
+	if b.allow() || b.State() != BreakerOpen {
+		t.Fatalf("breaker should be open again, got %s", b.State())
+	}
+
+	time.Sleep(openTimeout)
+	if !b.allow() {
+		t.Fatalf("breaker should be half-open, got %s", b.State())
+	}
+	b.success()
+	if !b.allow() || b.State() != BreakerClosed {
+		t.Fatalf("breaker should be closed, got %s", b.State())
+	}
+
+	expected := []string{"open", "half-open", "open", "half-open", "closed"}
+	if diff := cmp.Diff(expected, obs.events); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	t.Parallel()
+
+	b := newCircuitBreaker("1", CircuitBreakerConfig{}, nil)
+	for i := 0; i < 10; i++ {
+		b.failure()
+	}
+	if !b.allow() {
+		t.Fatalf("disabled breaker should allow requests")
+	}
+}
+
+func TestIsNodeFailure(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		err      error
+		expected bool
+	}{
+		{err: nil, expected: false},
+		{err: fmt.Errorf("dial: %w", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}), expected: true},
+		{err: fmt.Errorf("read header: %w", io.EOF), expected: true},
+		{err: fmt.Errorf("read header: %w", net.ErrClosed), expected: false},
+		{err: fmt.Errorf("[addr=1 shard=0] closed"), expected: false},
+		{err: fmt.Errorf("stream ID alloc: %w", errAllStreamsBusy), expected: false},
+		{err: ScyllaError{Code: frame.ErrCodeOverloaded}, expected: true},
+		{err: ScyllaError{Code: frame.ErrCodeBootstrapping}, expected: true},
+		{err: fmt.Errorf("read header: %w", &net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), expected: true},
+		{err: UnavailableError{ScyllaError: ScyllaError{Code: frame.ErrCodeUnavailable}}, expected: false},
+		{err: ReadTimeoutError{ScyllaError: ScyllaError{Code: frame.ErrCodeReadTimeout}}, expected: false},
+		{err: WriteTimeoutError{ScyllaError: ScyllaError{Code: frame.ErrCodeWriteTimeout}}, expected: false},
+		{err: ScyllaError{Code: frame.ErrCodeSyntax}, expected: false},
+	}
+
+	for _, tc := range testCases {
+		if v := isNodeFailure(tc.err); v != tc.expected {
+			t.Fatalf("isNodeFailure(%v) = %t, expected %t", tc.err, v, tc.expected)
+		}
+	}
+}
@@ -2,11 +2,11 @@ package transport
 
 import (
 	"fmt"
-	"log"
 	"net"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
@@ -31,8 +31,15 @@ type Cluster struct {
 	handledEvents     []frame.EventType // This will probably be moved to config.
 	knownHosts        map[string]struct{}
 	refreshChan       requestChan
+	schemaChan        requestChan
 	reopenControlChan requestChan
 	closeChan         requestChan
+	done              chan struct{} // closed when cluster loop exits
+	closedPools       []*ConnPool   // pools of removed nodes, cluster waits for them on close
+	schemaChanges     schemaChanges
+	subscribers       subscribers
+	hostMu            sync.Mutex // hostMu serializes HostListener callbacks made by cluster loop and control connection
+	replicas          replicaCache
 
 	queryInfoCounter atomic.Uint64
 }
@@ -60,9 +67,11 @@ const (
 	networkTopologyStrategyJCN strategyClass = "org.apache.cassandra.locator.NetworkTopologyStrategy"
 	simpleStrategyJCN          strategyClass = "org.apache.cassandra.locator.SimpleStrategy"
 	localStrategyJCN           strategyClass = "org.apache.cassandra.locator.LocalStrategy"
+	everywhereStrategyJCN      strategyClass = "org.apache.cassandra.locator.EverywhereStrategy"
 	networkTopologyStrategy    strategyClass = "NetworkTopologyStrategy"
 	simpleStrategy             strategyClass = "SimpleStrategy"
 	localStrategy              strategyClass = "LocalStrategy"
+	everywhereStrategy         strategyClass = "EverywhereStrategy"
 )
 
 type strategy struct {
@@ -72,6 +81,16 @@ type strategy struct {
 	data  map[string]string // Used in other strategy.
 }
 
+// known returns true if replicas of the strategy can be computed by the driver.
+func (s strategy) known() bool {
+	switch s.class {
+	case simpleStrategy, localStrategy, everywhereStrategy, networkTopologyStrategy:
+		return true
+	default:
+		return false
+	}
+}
+
 // QueryInfo represents data required for host selection policy to create query plan.
 // Token and strategy are only necessary for token aware policies.
 type QueryInfo struct {
@@ -79,7 +98,8 @@ type QueryInfo struct {
 	token      Token
 	topology   *topology
 	strategy   strategy
-	offset     uint64 // For round robin strategies.
+	offset     uint64     // For round robin strategies.
+	plans      *planCache // Plans built by policies for this query.
 }
 
 func (c *Cluster) NewQueryInfo() QueryInfo {
@@ -87,6 +107,7 @@ func (c *Cluster) NewQueryInfo() QueryInfo {
 		tokenAware: false,
 		topology:   c.Topology(),
 		offset:     c.generateOffset(),
+		plans:      new(planCache),
 	}
 }
 
@@ -103,15 +124,20 @@ func (c *Cluster) NewTokenAwareQueryInfo(t Token, ks string) (QueryInfo, error)
 			topology:   top,
 			strategy:   stg.strategy,
 			offset:     c.generateOffset(),
+			plans:      new(planCache),
 		}, nil
 	} else {
-		var allKs []string
-		for k := range top.keyspaces {
-			allKs = append(allKs, k)
-		}
-		sort.Strings(allKs)
-		return QueryInfo{}, fmt.Errorf("couldn't find keyspace %q in current topology, known keyspaces are: %s", ks, strings.Join(allKs, ", "))
+		return QueryInfo{}, top.unknownKeyspaceError(ks)
+	}
+}
+
+func (t *topology) unknownKeyspaceError(ks string) error {
+	var allKs []string
+	for k := range t.keyspaces {
+		allKs = append(allKs, k)
 	}
+	sort.Strings(allKs)
+	return fmt.Errorf("couldn't find keyspace %q in current topology, known keyspaces are: %s", ks, strings.Join(allKs, ", "))
 }
 
 // TODO overflow and negative modulo.
@@ -121,6 +147,12 @@ func (c *Cluster) generateOffset() uint64 {
 
 // NewCluster also creates control connection and starts handling events and refreshing topology.
 func NewCluster(cfg ConnConfig, p HostSelectionPolicy, e []frame.EventType, hosts ...string) (*Cluster, error) {
+	// Default logging observer reports to the configured logger.
+	if o, ok := cfg.ConnObserver.(LoggingConnObserver); ok && o.Logger == nil {
+		o.Logger = cfg.logger()
+		cfg.ConnObserver = o
+	}
+
 	kh := make(map[string]struct{}, len(hosts))
 	for _, h := range hosts {
 		kh[h] = struct{}{}
@@ -131,15 +163,13 @@ func NewCluster(cfg ConnConfig, p HostSelectionPolicy, e []frame.EventType, host
 		handledEvents:     e,
 		knownHosts:        kh,
 		refreshChan:       make(requestChan, 1),
+		schemaChan:        make(requestChan, 1),
 		reopenControlChan: make(requestChan, 1),
 		closeChan:         make(requestChan, 1),
+		done:              make(chan struct{}),
 	}
 
-	localDC := ""
-	if p, ok := p.(*TokenAwarePolicy); ok {
-		localDC = p.localDC
-	}
-	c.setTopology(&topology{localDC: localDC})
+	c.setTopology(&topology{localDC: localDCOf(p)})
 
 	if control, err := c.NewControl(); err != nil {
 		return nil, fmt.Errorf("create control connection: %w", err)
@@ -155,7 +185,7 @@ func NewCluster(cfg ConnConfig, p HostSelectionPolicy, e []frame.EventType, host
 }
 
 func (c *Cluster) NewControl() (*Conn, error) {
-	log.Printf("cluster: open control connection")
+	c.cfg.logger().Info("cluster: open control connection")
 	var errs []string
 	for addr := range c.knownHosts {
 		conn, err := OpenConn(addr, nil, c.cfg)
@@ -178,8 +208,16 @@ func (c *Cluster) NewControl() (*Conn, error) {
 
 // refreshTopology creates new topology filled with the result of keyspaceQuery, localQuery and peerQuery.
 // Old topology is replaced with the new one atomically to prevent dirty reads.
-func (c *Cluster) refreshTopology() error {
-	log.Printf("cluster: refresh topology")
+func (c *Cluster) refreshTopology() (err error) {
+	c.cfg.logger().Debug("cluster: refresh topology")
+	span := startSpan()
+	defer func() {
+		span.stop()
+		if o := c.cfg.ClusterObserver; o != nil {
+			o.OnTopologyRefresh(TopologyRefreshEvent{span: span, Err: err})
+		}
+	}()
+
 	rows, err := c.getAllNodesInfo()
 	if err != nil {
 		return fmt.Errorf("query info about nodes in cluster: %w", err)
@@ -205,11 +243,11 @@ func (c *Cluster) refreshTopology() error {
 			return err
 		}
 		// If node is present in both maps we can reuse its connection pool.
-		if node, ok := old[n.addr]; ok {
+		if node, ok := old[n.addr]; ok && node.pool != nil {
 			n.pool = node.pool
 			n.setStatus(node.Status())
 		} else {
-			if pool, err := NewConnPool(n.addr, c.cfg); err != nil {
+			if pool, err := NewConnPool(n.addr, c.cfg.forNode(n.hostID, n.datacenter)); err != nil {
 				n.setStatus(statusDown)
 			} else {
 				n.setStatus(statusUP)
@@ -217,7 +255,7 @@ func (c *Cluster) refreshTopology() error {
 			}
 		}
 		// Every encountered node becomes known host for future use.
-		c.knownHosts[n.addr] = struct{}{}
+		c.knownHosts[c.cfg.nodeAddr(n.addr)] = struct{}{}
 		t.peers[n.addr] = n
 		t.nodes = append(t.nodes, n)
 		u[uniqueRack{dc: n.datacenter, rack: n.rack}] = struct{}{}
@@ -230,23 +268,74 @@ func (c *Cluster) refreshTopology() error {
 		t.dcRacks[k.dc]++
 	}
 	// We want to close pools of nodes present in previous and absent in current topology.
+	c.pruneClosedPools()
 	for k, v := range old {
 		if _, ok := t.peers[k]; v.pool != nil && !ok {
 			v.pool.Close()
+			c.closedPools = append(c.closedPools, v.pool)
 		}
 	}
 
 	if ks, ok := t.keyspaces[c.cfg.Keyspace]; ok {
-		t.policyInfo.Preprocess(t, ks)
+		if !t.policyInfo.Preprocess(t, ks) {
+			c.cfg.logger().Warn("cluster: unknown replication strategy, defaulting to round robin", "keyspace", c.cfg.Keyspace, "class", ks.strategy.class)
+		}
 	} else {
 		t.policyInfo.Preprocess(t, keyspace{})
 	}
 
 	c.setTopology(t)
 	drainChan(c.refreshChan)
+	c.notifyHostChanges(old, t.peers)
+	c.subscribers.publishPending()
 	return nil
 }
 
+// notifyHostChanges reports nodes present only in one of the peer maps to HostListener.
+func (c *Cluster) notifyHostChanges(old, cur peerMap) {
+	l := c.cfg.HostListener
+	if l == nil {
+		return
+	}
+	c.hostMu.Lock()
+	defer c.hostMu.Unlock()
+	for k, v := range old {
+		if _, ok := cur[k]; !ok {
+			l.OnRemove(v.Info())
+		}
+	}
+	for k, v := range cur {
+		if _, ok := old[k]; !ok {
+			l.OnAdd(v.Info())
+		}
+	}
+}
+
+// Hosts returns snapshot of all nodes in current topology.
+func (c *Cluster) Hosts() []HostInfo {
+	nodes := c.Topology().nodes
+	res := make([]HostInfo, len(nodes))
+	for i, n := range nodes {
+		res[i] = n.Info()
+	}
+	return res
+}
+
+// Node returns node with given address or host ID in the canonical UUID format.
+func (c *Cluster) Node(host string) (*Node, error) {
+	t := c.Topology()
+	if n, ok := t.peers[host]; ok {
+		return n, nil
+	}
+	host = strings.ToLower(host)
+	for _, n := range t.nodes {
+		if formatUUID(n.hostID) == host {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNoSuchHost, host)
+}
+
 func newTopology() *topology {
 	return &topology{
 		peers:   make(peerMap),
@@ -380,6 +469,10 @@ func parseStrategyFromRow(r frame.Row) (strategy, error) {
 			class: localStrategy,
 			rf:    1,
 		}, nil
+	case everywhereStrategyJCN, everywhereStrategy:
+		return strategy{
+			class: everywhereStrategy,
+		}, nil
 	default:
 		return strategy{
 			class: strategyClass(className),
@@ -450,7 +543,7 @@ func (c *Cluster) setTopology(t *topology) {
 // of registering handlers for them.
 func (c *Cluster) handleEvent(r response) {
 	if r.Err != nil {
-		log.Printf("cluster: received event with error: %v", r.Err)
+		c.cfg.logger().Warn("cluster: received event with error", "error", r.Err)
 		c.RequestReopenControl()
 		return
 	}
@@ -460,49 +553,76 @@ func (c *Cluster) handleEvent(r response) {
 	case *StatusChange:
 		c.handleStatusChange(v)
 	case *SchemaChange:
-		// TODO: add schema change.
+		c.handleSchemaChange(v)
 	default:
-		log.Printf("cluster: unsupported event type: %v", r.Response)
+		c.cfg.logger().Warn("cluster: unsupported event type", "event", r.Response)
 	}
 }
 
 func (c *Cluster) handleTopologyChange(v *TopologyChange) {
-	log.Printf("cluster: handle topology change: %+#v", v)
+	c.cfg.logger().Info("cluster: handle topology change", "change", v.Change, "addr", v.Address)
+	c.subscribers.postpone(TopologyChangeEvent{
+		Change:  v.Change,
+		Address: net.IP(v.Address.IP),
+	})
 	c.RequestRefresh()
 }
 
 func (c *Cluster) handleStatusChange(v *StatusChange) {
-	log.Printf("cluster: handle status change: %+#v", v)
+	c.cfg.logger().Info("cluster: handle status change", "status", v.Status, "addr", v.Address)
+	ev := StatusChangeEvent{
+		Status:  v.Status,
+		Address: net.IP(v.Address.IP),
+	}
 	m := c.Topology().peers
 	addr := v.Address.String()
 	if n, ok := m[addr]; ok {
 		switch v.Status {
-		case frame.Up:
-			n.setStatus(statusUP)
-		case frame.Down:
-			n.setStatus(statusDown)
+		case frame.Up, frame.Down:
+			c.setNodeStatus(n, v.Status == frame.Up)
 		default:
-			log.Printf("cluster: status change not supported: %+#v", v)
+			c.cfg.logger().Warn("cluster: status change not supported", "status", v.Status, "addr", v.Address)
+			return
 		}
+		c.subscribers.publish(ev)
 	} else {
-		log.Printf("cluster: unknown node %s received status change: %+#v in topology %v", addr, v, m)
+		c.cfg.logger().Warn("cluster: unknown node received status change", "addr", addr, "status", v.Status)
+		c.subscribers.postpone(ev)
 		c.RequestRefresh()
 	}
 }
 
+// setNodeStatus changes status of n and reports the change to HostListener.
+func (c *Cluster) setNodeStatus(n *Node, up bool) {
+	c.hostMu.Lock()
+	defer c.hostMu.Unlock()
+
+	l := c.cfg.HostListener
+	switch {
+	case up && n.setStatus(statusUP) && l != nil:
+		l.OnUp(n.Info())
+	case !up && n.setStatus(statusDown) && l != nil:
+		l.OnDown(n.Info())
+	}
+}
+
 const refreshInterval = 60 * time.Second
 
 // loop handles cluster requests.
 func (c *Cluster) loop() {
 	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-c.refreshChan:
 			c.tryRefresh()
+		case <-c.schemaChan:
+			c.tryRefreshSchema()
 		case <-c.reopenControlChan:
 			c.tryReopenControl()
 		case <-c.closeChan:
 			c.handleClose()
+			close(c.done)
 			return
 		case <-ticker.C:
 			c.tryRefresh()
@@ -518,17 +638,23 @@ func (c *Cluster) tryRefresh() {
 	if err := c.refreshTopology(); err != nil {
 		c.RequestReopenControl()
 		time.AfterFunc(tryRefreshInterval, c.RequestRefresh)
-		log.Printf("cluster: refresh topology: %v", err)
+		c.cfg.logger().Error("cluster: refresh topology failed", "error", err)
 	}
 }
 
 const tryReopenControlInterval = time.Second
 
 func (c *Cluster) tryReopenControl() {
-	log.Printf("cluster: reopen control connection")
-	if control, err := c.NewControl(); err != nil {
+	c.cfg.logger().Info("cluster: reopen control connection")
+	span := startSpan()
+	control, err := c.NewControl()
+	span.stop()
+	if o := c.cfg.ClusterObserver; o != nil {
+		o.OnControlReopen(ControlReopenEvent{span: span, Err: err})
+	}
+	if err != nil {
 		time.AfterFunc(tryReopenControlInterval, c.RequestReopenControl)
-		log.Printf("cluster: failed to reopen control connection: %v", err)
+		c.cfg.logger().Error("cluster: failed to reopen control connection", "error", err)
 	} else {
 		c.control.Close()
 		c.control = control
@@ -537,7 +663,8 @@ func (c *Cluster) tryReopenControl() {
 }
 
 func (c *Cluster) handleClose() {
-	log.Printf("cluster: handle cluster close")
+	c.cfg.logger().Info("cluster: handle cluster close")
+	c.schemaChanges.stop()
 	c.control.Close()
 	m := c.Topology().peers
 	for _, v := range m {
@@ -545,30 +672,78 @@ func (c *Cluster) handleClose() {
 			v.pool.Close()
 		}
 	}
+	c.control.wait()
+	for _, v := range m {
+		if v.pool != nil {
+			v.pool.Wait()
+		}
+	}
+	for _, p := range c.closedPools {
+		p.Wait()
+	}
+}
+
+// pruneClosedPools forgets pools of removed nodes which are already closed.
+func (c *Cluster) pruneClosedPools() {
+	closing := c.closedPools[:0]
+	for _, p := range c.closedPools {
+		select {
+		case <-p.done:
+		default:
+			closing = append(closing, p)
+		}
+	}
+	c.closedPools = closing
+}
+
+// Subscribe registers handler for cluster events, it returns a function that removes the handler.
+// Only events the control connection is registered for are delivered. Event is delivered
+// after it has been applied to the cluster topology or schema.
+func (c *Cluster) Subscribe(h EventHandler) func() {
+	return c.subscribers.subscribe(h)
+}
+
+// Waiting returns number of requests queued or in flight on all nodes.
+func (c *Cluster) Waiting() int {
+	n := 0
+	for _, v := range c.Topology().nodes {
+		n += v.Waiting()
+	}
+	return n
 }
 
 func (c *Cluster) RequestRefresh() {
-	log.Printf("cluster: requested to refresh cluster topology")
+	c.cfg.logger().Debug("cluster: requested to refresh cluster topology")
 	select {
 	case c.refreshChan <- struct{}{}:
 	default:
 	}
 }
 
+func (c *Cluster) RequestSchemaRefresh() {
+	c.cfg.logger().Debug("cluster: requested to refresh schema")
+	select {
+	case c.schemaChan <- struct{}{}:
+	default:
+	}
+}
+
 func (c *Cluster) RequestReopenControl() {
-	log.Printf("cluster: requested to reopen control connection")
+	c.cfg.logger().Debug("cluster: requested to reopen control connection")
 	select {
 	case c.reopenControlChan <- struct{}{}:
 	default:
 	}
 }
 
+// Close closes control connection and all pools, it waits for all cluster go routines to exit.
 func (c *Cluster) Close() {
-	log.Printf("cluster: requested to close cluster")
+	c.cfg.logger().Debug("cluster: requested to close cluster")
 	select {
 	case c.closeChan <- struct{}{}:
 	default:
 	}
+	<-c.done
 }
 
 func drainChan(c requestChan) {
@@ -95,3 +95,39 @@ func TestClusterIntegration(t *testing.T) {
 	c.Close()
 	time.Sleep(awaitingChanges)
 }
+
+func TestClusterSchemaChangeIntegration(t *testing.T) {
+	c, err := NewCluster(DefaultConnConfig(""), NewTokenAwarePolicy(""), []string{frame.SchemaChange}, TestHost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	const ks = "schema_change_test"
+	if _, err := c.control.Query(makeStatement("DROP KEYSPACE IF EXISTS "+ks), nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(schemaRefreshDelay + awaitingChanges)
+	if _, ok := c.Topology().keyspaces[ks]; ok {
+		t.Fatalf("keyspace %s should be dropped", ks)
+	}
+
+	stmt := "CREATE KEYSPACE " + ks + " WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 1}"
+	if _, err := c.control.Query(makeStatement(stmt), nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(schemaRefreshDelay + awaitingChanges)
+	if k, ok := c.Topology().keyspaces[ks]; !ok || k.strategy.rf != 1 {
+		t.Fatalf("keyspace %s not loaded: %+v", ks, k)
+	}
+
+	stmt = "ALTER KEYSPACE " + ks + " WITH replication = {'class': 'SimpleStrategy', 'replication_factor': 2}"
+	if _, err := c.control.Query(makeStatement(stmt), nil); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(schemaRefreshDelay + awaitingChanges)
+	if k := c.Topology().keyspaces[ks]; k.strategy.rf != 2 {
+		t.Fatalf("keyspace %s replication not updated: %+v", ks, k)
+	}
+
+}
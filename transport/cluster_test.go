@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"go.uber.org/atomic"
+)
+
+type recordingHostListener struct {
+	events []string
+}
+
+func (l *recordingHostListener) OnAdd(h HostInfo)    { l.events = append(l.events, "add "+h.Addr) }
+func (l *recordingHostListener) OnRemove(h HostInfo) { l.events = append(l.events, "remove "+h.Addr) }
+func (l *recordingHostListener) OnUp(h HostInfo)     { l.events = append(l.events, "up "+h.Addr) }
+func (l *recordingHostListener) OnDown(h HostInfo)   { l.events = append(l.events, "down "+h.Addr) }
+
+func TestClusterNotifyHostChanges(t *testing.T) {
+	t.Parallel()
+
+	l := &recordingHostListener{}
+	c := Cluster{cfg: ConnConfig{HostListener: l}}
+
+	old := peerMap{
+		"1": {addr: "1"},
+		"2": {addr: "2"},
+	}
+	cur := peerMap{
+		"2": {addr: "2"},
+		"3": {addr: "3"},
+	}
+	c.notifyHostChanges(old, cur)
+	sort.Strings(l.events)
+
+	if diff := cmp.Diff([]string{"add 3", "remove 1"}, l.events); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestClusterHosts(t *testing.T) {
+	t.Parallel()
+
+	c := mockCluster(mockTopologyRoundRobin(), "", "")
+	c.Topology().nodes[1].setStatus(statusDown)
+
+	hosts := c.Hosts()
+	if len(hosts) != 5 {
+		t.Fatalf("expected 5 hosts, got %+v", hosts)
+	}
+	if h := hosts[0]; h.Addr != "1" || h.Datacenter != "eu" || !h.Up {
+		t.Fatalf("unexpected host info %+v", h)
+	}
+	if h := hosts[1]; h.Up {
+		t.Fatalf("unexpected host info %+v", h)
+	}
+
+	// Host info is a snapshot.
+	c.Topology().nodes[0].setStatus(statusDown)
+	if !hosts[0].Up {
+		t.Fatalf("host info changed after node status change")
+	}
+}
+
+func TestClusterReplicas(t *testing.T) {
+	t.Parallel()
+
+	top := mockTopologyTokenAwareDCAwareStrategy()
+	top.keyspaces["everywhere"] = keyspace{strategy: strategy{class: everywhereStrategy}}
+	top.keyspaces["simple"] = keyspace{strategy: strategy{class: simpleStrategy, rf: 1}}
+	top.keyspaces["custom"] = keyspace{strategy: strategy{class: "com.example.CustomStrategy"}}
+	c := mockCluster(top, "waw/her", "waw")
+	c.cfg.Keyspace = "waw/her"
+	top.nodes[0].pool = &ConnPool{nrShards: 4, msbIgnore: 12}
+
+	testCases := []struct {
+		name     string
+		keyspace string
+		expected []string
+	}{
+		{
+			name:     "default keyspace",
+			expected: []string{"1", "4", "5", "6", "8"},
+		},
+		{
+			name:     "other keyspace",
+			keyspace: "everywhere",
+			expected: []string{"1", "2", "4", "3", "5", "6", "8", "7"},
+		},
+		{
+			name:     "simple strategy keyspace",
+			keyspace: "simple",
+			expected: []string{"1"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := c.Replicas(tc.keyspace, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range res {
+				got = append(got, r.Host.Addr)
+				expected := -1
+				if r.Host.Addr == "1" {
+					expected = top.nodes[0].pool.shardOf(0)
+				}
+				if r.Shard != expected {
+					t.Fatalf("node %s: got shard %d, expected %d", r.Host.Addr, r.Shard, expected)
+				}
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Fatal(diff)
+			}
+		})
+	}
+
+	if _, err := c.Replicas("everywhere", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.replicas.infos["everywhere"]; !ok || c.replicas.topology != c.Topology() {
+		t.Fatal("expected replicas of other keyspace to be cached")
+	}
+
+	if _, err := c.Replicas("unknown", 0); err == nil {
+		t.Fatal("expected error for unknown keyspace")
+	}
+	if _, err := c.Replicas("custom", 0); err == nil {
+		t.Fatal("expected error for keyspace with unsupported strategy")
+	}
+	if err := c.CheckKeyspace("unknown"); err == nil {
+		t.Fatal("expected error for unknown keyspace")
+	}
+}
+
+func TestClusterNode(t *testing.T) {
+	t.Parallel()
+
+	top := mockTopologyTokenAwareDCAwareStrategy()
+	top.peers = make(peerMap)
+	for _, n := range top.nodes {
+		top.peers[n.addr] = n
+	}
+	top.nodes[1].hostID = frame.UUID{0xab, 0xcd, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14}
+	c := mockCluster(top, "", "")
+
+	testCases := []struct {
+		name     string
+		host     string
+		expected string
+		err      error
+	}{
+		{
+			name:     "address",
+			host:     "3",
+			expected: "3",
+		},
+		{
+			name:     "host id",
+			host:     "ABCD0102-0304-0506-0708-090a0b0c0d0e",
+			expected: "2",
+		},
+		{
+			name: "unknown",
+			host: "10.0.0.1",
+			err:  ErrNoSuchHost,
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			n, err := c.Node(tc.host)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("got error %v, expected %v", err, tc.err)
+			}
+			if tc.err == nil && n.addr != tc.expected {
+				t.Fatalf("got node %s, expected %s", n.addr, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNodeShardConn(t *testing.T) {
+	t.Parallel()
+
+	n := &Node{addr: "1"}
+	if _, err := n.ShardConn(0); !errors.Is(err, ErrHostNotConnected) {
+		t.Fatalf("got error %v, expected %v", err, ErrHostNotConnected)
+	}
+
+	n.pool = &ConnPool{conns: make([]atomic.Value, 2)}
+	conn := new(Conn)
+	n.pool.conns[1].Store(conn)
+	if _, err := n.ShardConn(0); !errors.Is(err, ErrHostNotConnected) {
+		t.Fatalf("got error %v, expected %v", err, ErrHostNotConnected)
+	}
+	if _, err := n.ShardConn(2); err == nil {
+		t.Fatal("expected error for shard out of range")
+	}
+	if v, err := n.ShardConn(1); err != nil || v != conn {
+		t.Fatalf("got %v %v, expected shard 1 connection", v, err)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"strconv"
 	"strings"
@@ -26,6 +25,9 @@ type response struct {
 	frame.Header
 	frame.Response
 	Err error
+	// Received is the time the response was read from connection,
+	// it's zero for errors reported by the driver.
+	Received time.Time
 }
 
 type ResponseHandler chan response
@@ -41,8 +43,9 @@ type request struct {
 var _connCloseRequest = request{}
 
 type stats struct {
-	inFlight atomic.Uint32
-	inQueue  atomic.Uint32
+	inFlight     atomic.Uint32
+	inQueue      atomic.Uint32
+	lastReceived atomic.Int64 // unix nano time of the last frame received
 }
 
 type connWriter struct {
@@ -51,8 +54,9 @@ type connWriter struct {
 	compr      *compr
 	requestCh  chan request
 	stats      *stats
+	logger     Logger
 	connString func() string
-	connClose  func()
+	connClose  func(err error)
 }
 
 func (c *connWriter) submit(r request) {
@@ -82,16 +86,16 @@ func (c *connWriter) loop() {
 			}
 			c.stats.inQueue.Dec()
 			if err := c.send(r); err != nil {
-				log.Printf("%s fatal send error, closing connection due to %s", c.connString(), err)
+				c.logger.Error("send failed, closing connection", "conn", c.connString(), "error", err)
 				r.ResponseHandler <- response{Err: fmt.Errorf("%s send: %w", c.connString(), err)}
-				c.connClose()
+				c.connClose(err)
 				return
 			}
 			c.stats.inFlight.Inc()
 		}
 		if err := c.conn.Flush(); err != nil {
-			log.Printf("%s fatal flush error, closing connection due to %s", c.connString(), err)
-			c.connClose()
+			c.logger.Error("flush failed, closing connection", "conn", c.connString(), "error", err)
+			c.connClose(err)
 			return
 		}
 	}
@@ -134,20 +138,69 @@ type connReader struct {
 	bufw        io.Writer
 	stats       *stats
 	compr       *compr
+	logger      Logger
 	handleEvent func(r response)
 	connString  func() string
-	connClose   func()
+	connClose   func(err error)
+	onExhausted func(waiting int, rejected bool)
 
 	h      map[frame.StreamID]ResponseHandler
 	s      streamIDAllocator
 	closed bool
-	mu     sync.Mutex // mu guards h, s and closed
+	mu     sync.Mutex // mu guards h, s, closed and streamWaiters
+
+	// Requests that can't get a stream ID wait in a bounded queue until a stream ID is freed.
+	streamFreed       chan struct{}
+	streamWaiters     int
+	maxStreamWaiters  int
+	streamWaitTimeout time.Duration
 }
 
+// setHandler allocates stream ID for h. If all stream IDs are busy it waits
+// for a stream ID to be freed unless the wait queue is full.
 func (c *connReader) setHandler(h ResponseHandler) (frame.StreamID, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	streamID, err := c.setHandlerLocked(h)
+	if !errors.Is(err, errAllStreamsBusy) {
+		c.mu.Unlock()
+		return streamID, err
+	}
+	if c.streamWaiters >= c.maxStreamWaiters {
+		waiting := c.streamWaiters
+		c.mu.Unlock()
+		c.exhausted(waiting, true)
+		return streamID, err
+	}
+	c.streamWaiters++
+	waiting := c.streamWaiters
+	c.mu.Unlock()
+	c.exhausted(waiting, false)
 
+	defer func() {
+		c.mu.Lock()
+		c.streamWaiters--
+		c.mu.Unlock()
+	}()
+
+	timer := time.NewTimer(c.streamWaitTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.streamFreed:
+			c.mu.Lock()
+			streamID, err = c.setHandlerLocked(h)
+			c.mu.Unlock()
+			if !errors.Is(err, errAllStreamsBusy) {
+				return streamID, err
+			}
+		case <-timer.C:
+			c.exhausted(waiting, true)
+			return streamID, err
+		}
+	}
+}
+
+func (c *connReader) setHandlerLocked(h ResponseHandler) (frame.StreamID, error) {
 	if c.closed {
 		return invalidStreamID, fmt.Errorf("%s closed", c.connString())
 	}
@@ -161,12 +214,24 @@ func (c *connReader) setHandler(h ResponseHandler) (frame.StreamID, error) {
 	return streamID, err
 }
 
+func (c *connReader) exhausted(waiting int, rejected bool) {
+	if c.onExhausted != nil {
+		c.onExhausted(waiting, rejected)
+	}
+}
+
 // handler free given streamID and return corresponding handler.
 func (c *connReader) handler(streamID frame.StreamID) ResponseHandler {
 	c.mu.Lock()
 	h := c.h[streamID]
 	c.s.Free(streamID)
 	delete(c.h, streamID)
+	if c.streamWaiters > 0 {
+		select {
+		case c.streamFreed <- struct{}{}:
+		default:
+		}
+	}
 	c.mu.Unlock()
 	return h
 }
@@ -175,6 +240,8 @@ func (c *connReader) loop() {
 	c.bufw = frame.BufferWriter(&c.buf)
 	for {
 		resp := c.recv()
+		resp.Received = Now()
+		c.stats.lastReceived.Store(resp.Received.UnixNano())
 		if resp.StreamID == eventStreamID {
 			if c.handleEvent != nil {
 				c.handleEvent(resp)
@@ -183,8 +250,8 @@ func (c *connReader) loop() {
 		}
 
 		if resp.Err != nil {
-			log.Printf("%s fatal receive error, closing connection due to %s", c.connString(), resp.Err)
-			c.connClose()
+			c.logger.Warn("receive failed, closing connection", "conn", c.connString(), "error", resp.Err)
+			c.connClose(resp.Err)
 			c.drainHandlers()
 			return
 		}
@@ -194,8 +261,8 @@ func (c *connReader) loop() {
 		if h := c.handler(resp.StreamID); h != nil {
 			h <- resp
 		} else {
-			log.Printf("%s received unknown stream ID %d, closing connection", c.connString(), resp.StreamID)
-			c.connClose()
+			c.logger.Error("received unknown stream ID, closing connection", "conn", c.connString(), "stream_id", resp.StreamID)
+			c.connClose(nil)
 			c.drainHandlers()
 			return
 		}
@@ -235,7 +302,7 @@ func (c *connReader) recv() response {
 
 	r.Response = c.parse(r.Header.OpCode)
 	if r.Response == nil {
-		r.Err = fmt.Errorf("response type not supported")
+		r.Err = fmt.Errorf("response type %v not supported", r.Header.OpCode)
 		return r
 	}
 	if err := c.buf.Error(); err != nil {
@@ -252,6 +319,13 @@ func (c *connReader) drainHandlers() {
 	for _, h := range c.h {
 		h <- response{Err: fmt.Errorf("%s closed", c.connString())}
 	}
+	// Wake up requests waiting for stream ID, so that they get closed error.
+	for i := 0; i < c.streamWaiters; i++ {
+		select {
+		case c.streamFreed <- struct{}{}:
+		default:
+		}
+	}
 	c.mu.Unlock()
 }
 
@@ -275,7 +349,6 @@ func (c *connReader) parse(op frame.OpCode) frame.Response {
 	case frame.OpAuthChallenge:
 		return ParseAuthChallenge(&c.buf)
 	default:
-		log.Fatalf("not supported")
 		return nil
 	}
 }
@@ -288,7 +361,11 @@ type Conn struct {
 	r         connReader
 	stats     *stats
 	closeOnce sync.Once
-	onClose   func(conn *Conn)
+	done      chan struct{}  // closed when connection is closed
+	loops     sync.WaitGroup // reader, writer and heartbeat go routines
+	onClose   atomic.Value   // func(conn *Conn), it may be set after heartbeats are started
+	retired   atomic.Bool    // set when connection is replaced in pool and its close must not be reported
+	failed    atomic.Bool    // set when connection was closed because of transport error
 }
 
 type ConnConfig struct {
@@ -309,6 +386,42 @@ type ConnConfig struct {
 	ComprBufferSize int
 
 	ConnObserver ConnObserver
+
+	// ClusterObserver is notified about topology refreshes and control connection reopening, it's optional.
+	ClusterObserver ClusterObserver
+
+	// CircuitBreaker configures per node circuit breaker used to keep failing nodes out of query plans.
+	CircuitBreaker CircuitBreakerConfig
+
+	// HostListener is used by Cluster to report nodes state, it's optional.
+	HostListener HostListener
+
+	// AddressTranslator is applied to addresses of discovered nodes, it's optional.
+	AddressTranslator AddressTranslator
+
+	// SNIProxy makes all connections go through TLS SNI proxy, it requires TLSConfig.
+	SNIProxy *SNIProxyConfig
+
+	// Dialer opens connections, if not set NetDialer is used.
+	Dialer Dialer
+
+	// AuthProvider authenticates connections, if not set PasswordAuthenticator
+	// with Username and Password is used.
+	AuthProvider AuthProvider
+
+	// Logger is used to report connection and cluster state, if not set StdLogger is used.
+	// Frame parsing problems are reported to process wide logger set with frame.SetLogger.
+	Logger Logger
+
+	// CredentialsProvider if set is used instead of Username and Password.
+	CredentialsProvider CredentialsProvider
+
+	// HeartbeatInterval is the time after which an idle connection is checked with OPTIONS request,
+	// zero disables heartbeats.
+	HeartbeatInterval time.Duration
+	// HeartbeatTimeout is the time to wait for heartbeat response before the connection is closed,
+	// if not set HeartbeatInterval is used.
+	HeartbeatTimeout time.Duration
 }
 
 func DefaultConnConfig(keyspace string) ConnConfig {
@@ -320,14 +433,19 @@ func DefaultConnConfig(keyspace string) ConnConfig {
 		Timeout:            500 * time.Millisecond,
 		DefaultConsistency: frame.LOCALQUORUM,
 		DefaultPort:        "9042",
+		Logger:             StdLogger{},
 		ConnObserver:       LoggingConnObserver{},
 		ComprBufferSize:    comprBufferSize,
+		HeartbeatInterval:  30 * time.Second,
+		HeartbeatTimeout:   10 * time.Second,
 	}
 }
 
 const (
 	requestChanSize      = maxStreamID / 2
 	targetWaiting        = requestChanSize
+	maxStreamWaiters     = 1024
+	streamWaitTimeout    = time.Second
 	maxCoalescedRequests = 100
 	ioBufferSize         = 8192
 	comprBufferSize      = 64 * 1024 // 64 Kb
@@ -340,7 +458,7 @@ func OpenShardConn(addr string, si ShardInfo, cfg ConnConfig) (*Conn, error) {
 	for i := 0; i < maxTries; i++ {
 		conn, err := OpenLocalPortConn(addr, it(), cfg)
 		if err != nil {
-			log.Printf("%s dial error: %s (try %d/%d)", addr, err, i, maxTries)
+			cfg.logger().Warn("dial failed", "addr", addr, "error", err, "try", i, "max_tries", maxTries)
 			if conn != nil {
 				conn.Close()
 			}
@@ -369,22 +487,23 @@ func OpenLocalPortConn(addr string, localPort uint16, cfg ConnConfig) (*Conn, er
 //
 // If error and connection are returned the connection is not valid and must be closed by the caller.
 func OpenConn(addr string, localAddr *net.TCPAddr, cfg ConnConfig) (*Conn, error) {
-	d := net.Dialer{
-		Timeout:   cfg.Timeout,
-		LocalAddr: localAddr,
+	if cfg.SNIProxy != nil {
+		addr = cfg.SNIProxy.Addr
 	}
-	conn, err := d.Dial("tcp", withPort(addr, cfg.DefaultPort))
+	conn, err := cfg.dial(withPort(addr, cfg.DefaultPort), localAddr)
 	if err != nil {
 		return nil, fmt.Errorf("dial TCP address %s: %w", addr, err)
 	}
 
-	tcpConn := conn.(*net.TCPConn)
-	if err := tcpConn.SetNoDelay(cfg.TCPNoDelay); err != nil {
-		return nil, fmt.Errorf("set TCP no delay option: %w", err)
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		if err := tcpConn.SetNoDelay(cfg.TCPNoDelay); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("set TCP no delay option: %w", err)
+		}
 	}
 
 	if cfg.TLSConfig != nil {
-		tConn, err := WrapTLS(tcpConn, cfg.TLSConfig)
+		tConn, err := wrapTLS(conn, cfg.TLSConfig, cfg.logger())
 		if err != nil {
 			return nil, err
 		}
@@ -392,17 +511,21 @@ func OpenConn(addr string, localAddr *net.TCPAddr, cfg ConnConfig) (*Conn, error
 		return WrapConn(tConn, cfg)
 	}
 
-	return WrapConn(tcpConn, cfg)
+	return WrapConn(conn, cfg)
 }
 
-func WrapTLS(conn *net.TCPConn, cfg *tls.Config) (net.Conn, error) {
+func WrapTLS(conn net.Conn, cfg *tls.Config) (net.Conn, error) {
+	return wrapTLS(conn, cfg, StdLogger{})
+}
+
+func wrapTLS(conn net.Conn, cfg *tls.Config, logger Logger) (net.Conn, error) {
 	cfg = cfg.Clone()
 	tconn := tls.Client(conn, cfg)
 	if err := tconn.Handshake(); err != nil {
 		if err := tconn.Close(); err != nil {
-			log.Printf("%s failed to close: %s", tconn.RemoteAddr(), err)
+			logger.Warn("failed to close connection", "addr", tconn.RemoteAddr(), "error", err)
 		} else {
-			log.Printf("%s closed", tconn.RemoteAddr())
+			logger.Debug("connection closed", "addr", tconn.RemoteAddr())
 		}
 
 		return nil, err
@@ -427,20 +550,28 @@ func WrapConn(conn net.Conn, cfg ConnConfig) (*Conn, error) {
 			conn:       bufio.NewWriterSize(conn, ioBufferSize),
 			requestCh:  make(chan request, requestChanSize),
 			stats:      s,
+			logger:     cfg.logger(),
 			connString: c.String,
-			connClose:  c.Close,
+			connClose:  c.closeOnError,
 		},
 		r: connReader{
 			conn: io.LimitedReader{
 				R: bufio.NewReaderSize(conn, ioBufferSize),
 			},
-			stats:      s,
-			h:          make(map[frame.StreamID]ResponseHandler),
-			connString: c.String,
-			connClose:  c.Close,
+			stats:             s,
+			logger:            cfg.logger(),
+			h:                 make(map[frame.StreamID]ResponseHandler),
+			connString:        c.String,
+			connClose:         c.closeOnError,
+			onExhausted:       c.onStreamsExhausted,
+			streamFreed:       make(chan struct{}, maxStreamWaiters),
+			maxStreamWaiters:  maxStreamWaiters,
+			streamWaitTimeout: streamWaitTimeout,
 		},
 		stats: s,
+		done:  make(chan struct{}),
 	}
+	s.lastReceived.Store(Now().UnixNano())
 
 	if cfg.Compression != "" {
 		if compr, err := newCompr(false, cfg.Compression, cfg.ComprBufferSize); err != nil {
@@ -455,12 +586,26 @@ func WrapConn(conn net.Conn, cfg ConnConfig) (*Conn, error) {
 		}
 	}
 
-	go c.w.loop()
-	go c.r.loop()
+	c.loops.Add(2)
+	go func() {
+		defer c.loops.Done()
+		c.w.loop()
+	}()
+	go func() {
+		defer c.loops.Done()
+		c.r.loop()
+	}()
 
 	if err := c.init(); err != nil {
 		return c, err
 	}
+	if cfg.HeartbeatInterval > 0 {
+		c.loops.Add(1)
+		go func() {
+			defer c.loops.Done()
+			c.heartbeatLoop()
+		}()
+	}
 
 	return c, nil
 }
@@ -474,6 +619,9 @@ func (cfg *ConnConfig) validate() error {
 	if cfg.DefaultConsistency < frame.ANY || cfg.DefaultConsistency > frame.LOCALONE {
 		return fmt.Errorf("unknown consistency: %v", cfg.DefaultConsistency)
 	}
+	if cfg.SNIProxy != nil && cfg.TLSConfig == nil {
+		return fmt.Errorf("SNI proxy requires TLS config")
+	}
 	return nil
 }
 
@@ -540,33 +688,42 @@ func (c *Conn) Startup(options frame.StartupOptions) error {
 	}
 }
 
-// 'AllowAllAuthenticator' and 'org.apache.cassandra.auth.AllowAllAuthenticator' do not require authentication.
-var approvedAuthenticators = map[string]struct{}{
-	"PasswordAuthenticator":                           {},
-	"org.apache.cassandra.auth.PasswordAuthenticator": {},
-	"com.scylladb.auth.TransitionalAuthenticator":     {},
-}
+// maxAuthRounds limits the number of challenges during authentication.
+const maxAuthRounds = 64
 
 func (c *Conn) AuthResponse(a *Authenticate) error {
-	if _, ok := approvedAuthenticators[a.Name]; !ok {
-		return fmt.Errorf("authenticator %q not supported", a.Name)
-	}
-	req := AuthResponse{
-		Username: c.cfg.Username,
-		Password: c.cfg.Password,
+	auth, err := c.cfg.authProvider().NewAuthenticator(c.conn.RemoteAddr().String(), a.Name)
+	if err != nil {
+		return err
 	}
-	res, err := c.sendRequest(&req, false, false)
+	token, err := auth.InitialResponse()
 	if err != nil {
-		return fmt.Errorf("can't send auth response: %w", err)
+		return fmt.Errorf("initial auth response: %w", err)
 	}
-	switch v := res.(type) {
-	case *AuthSuccess:
-		return nil
-	case *AuthChallenge:
-		return fmt.Errorf("authentication challenge is not yet supported: %#+v", v)
-	default:
-		return responseAsError(v)
+	if token == nil {
+		token = []byte{}
+	}
+
+	for i := 0; i < maxAuthRounds; i++ {
+		res, err := c.sendRequest(&AuthResponse{Token: frame.Bytes(token)}, false, false)
+		if err != nil {
+			return fmt.Errorf("can't send auth response: %w", err)
+		}
+		switch v := res.(type) {
+		case *AuthSuccess:
+			return auth.Success(v.Token)
+		case *AuthChallenge:
+			if token, err = auth.Challenge(v.Token); err != nil {
+				return fmt.Errorf("auth challenge: %w", err)
+			}
+			if token == nil {
+				token = []byte{}
+			}
+		default:
+			return responseAsError(v)
+		}
 	}
+	return fmt.Errorf("authentication not finished after %d challenges", maxAuthRounds)
 }
 
 func (c *Conn) UseKeyspace(ks string) error {
@@ -668,17 +825,12 @@ func (c *Conn) sendRequest(req frame.Request, compress, tracing bool) (frame.Res
 }
 
 func (c *Conn) asyncSendRequest(req frame.Request, compress, tracing bool, h ResponseHandler) {
-control:
 	c.sendController()
 
 	streamID, err := c.r.setHandler(h)
 	if err != nil {
-		if errors.Is(err, errAllStreamsBusy) {
-			goto control
-		} else {
-			h <- response{Err: fmt.Errorf("set handler %w", err)}
-			return
-		}
+		h <- response{Err: fmt.Errorf("set handler: %w", err)}
+		return
 	}
 
 	r := request{
@@ -718,8 +870,18 @@ func (c *Conn) Waiting() int {
 	return int(c.stats.inQueue.Load() + c.stats.inFlight.Load())
 }
 
+func (c *Conn) onStreamsExhausted(waiting int, rejected bool) {
+	if o, ok := c.cfg.ConnObserver.(StreamsObserver); ok {
+		o.OnStreamsExhausted(StreamsExhaustedEvent{
+			ConnEvent: c.event,
+			Waiting:   waiting,
+			Rejected:  rejected,
+		})
+	}
+}
+
 func (c *Conn) setOnClose(f func(conn *Conn)) {
-	c.onClose = f
+	c.onClose.Store(f)
 }
 
 func (c *Conn) Event() ConnEvent {
@@ -732,19 +894,35 @@ func (c *Conn) Shard() int {
 
 // Close closes connection and terminates reader and writer go routines.
 func (c *Conn) Close() {
+	c.close(false)
+}
+
+// closeOnError closes connection after reader or writer failed with err.
+func (c *Conn) closeOnError(err error) {
+	c.close(isTransportError(err))
+}
+
+func (c *Conn) close(failed bool) {
 	c.closeOnce.Do(func() {
+		c.failed.Store(failed)
 		if err := c.conn.Close(); err != nil {
-			log.Printf("%s failed to close: %s", c, err)
+			c.cfg.logger().Warn("failed to close connection", "conn", c, "error", err)
 		} else {
-			log.Printf("%s closed", c)
+			c.cfg.logger().Debug("connection closed", "conn", c)
 		}
+		close(c.done)
 		c.w.requestCh <- _connCloseRequest
-		if c.onClose != nil {
-			c.onClose(c)
+		if f, _ := c.onClose.Load().(func(conn *Conn)); f != nil {
+			f(c)
 		}
 	})
 }
 
+// wait waits for reader, writer and heartbeat go routines to exit after Close.
+func (c *Conn) wait() {
+	c.loops.Wait()
+}
+
 func (c *Conn) String() string {
 	return fmt.Sprintf("[addr=%s shard=%d]", c.conn.RemoteAddr(), c.event.Shard)
 }
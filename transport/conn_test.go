@@ -1,7 +1,12 @@
 package transport
 
 import (
+	"crypto/tls"
+	"fmt"
+	"net"
 	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
 )
 
 func TestPortParsing(t *testing.T) {
@@ -49,3 +54,53 @@ func TestPortParsing(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenConnThroughSNIProxy(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	serverName := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tconn := tls.Server(conn, &tls.Config{
+			GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+				serverName <- hello.ServerName
+				return nil, fmt.Errorf("stop handshake")
+			},
+		})
+		_ = tconn.Handshake()
+	}()
+
+	cfg := DefaultConnConfig("")
+	cfg.TLSConfig = &tls.Config{ServerName: "cql.example.com"}
+	cfg.SNIProxy = &SNIProxyConfig{
+		Addr:       "127.0.0.1:1",
+		NodeDomain: "cql.example.com",
+		Datacenters: map[string]SNIProxyDatacenter{
+			"eu": {Addr: l.Addr().String(), NodeDomain: "eu.cql.example.com"},
+		},
+	}
+	hostID := frame.UUID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	if v := cfg.forNode(hostID, "us"); v.SNIProxy.Addr != "127.0.0.1:1" || v.TLSConfig.ServerName != "01020304-0506-0708-090a-0b0c0d0e0f10.cql.example.com" {
+		t.Fatalf("got proxy %+v and server name %q for node outside mapped datacenters", v.SNIProxy, v.TLSConfig.ServerName)
+	}
+	if _, err := OpenConn("10.0.0.1", nil, cfg.forNode(hostID, "eu")); err == nil {
+		t.Fatal("expected handshake error")
+	}
+	if v, expected := <-serverName, "01020304-0506-0708-090a-0b0c0d0e0f10.eu.cql.example.com"; v != expected {
+		t.Fatalf("got server name %q, expected %q", v, expected)
+	}
+	if cfg.TLSConfig.ServerName != "cql.example.com" {
+		t.Fatalf("base TLS config was modified, got server name %q", cfg.TLSConfig.ServerName)
+	}
+}
@@ -0,0 +1,75 @@
+package transport
+
+import (
+	"context"
+	"net"
+)
+
+// Dialer opens network connections to nodes, it's used for control and pool connections.
+// It's compatible with golang.org/x/net/proxy.ContextDialer so SOCKS5 dialers can be used directly.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// LocalAddrDialer is a Dialer which can bind connections to a local address.
+// Shard aware port is used only with dialers implementing it, otherwise connections
+// are opened to the regular port and are kept on shards they land on.
+type LocalAddrDialer interface {
+	Dialer
+	DialContextFrom(ctx context.Context, network string, localAddr *net.TCPAddr, addr string) (net.Conn, error)
+}
+
+// DialerFunc is an adapter allowing to use a function as Dialer.
+type DialerFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f DialerFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+// NetDialer is the default LocalAddrDialer based on net.Dialer, Dialer.LocalAddr is overridden
+// when dialing from a local address.
+type NetDialer struct {
+	net.Dialer
+}
+
+var _ LocalAddrDialer = (*NetDialer)(nil)
+
+func (d *NetDialer) DialContextFrom(ctx context.Context, network string, localAddr *net.TCPAddr, addr string) (net.Conn, error) {
+	v := d.Dialer
+	v.LocalAddr = localAddr
+	return v.DialContext(ctx, network, addr)
+}
+
+// dialer returns Dialer from config or NetDialer if it's not set.
+func (cfg ConnConfig) dialer() Dialer {
+	if cfg.Dialer != nil {
+		return cfg.Dialer
+	}
+	return &NetDialer{}
+}
+
+// shardAware returns true if connections can be opened to a chosen shard with shard aware port.
+func (cfg ConnConfig) shardAware() bool {
+	if cfg.SNIProxy != nil {
+		return false
+	}
+	_, ok := cfg.dialer().(LocalAddrDialer)
+	return ok
+}
+
+func (cfg ConnConfig) dial(addr string, localAddr *net.TCPAddr) (net.Conn, error) {
+	ctx := context.Background()
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+
+	d := cfg.dialer()
+	if localAddr != nil {
+		if ld, ok := d.(LocalAddrDialer); ok {
+			return ld.DialContextFrom(ctx, "tcp", localAddr, addr)
+		}
+	}
+	return d.DialContext(ctx, "tcp", addr)
+}
@@ -0,0 +1,82 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+var errTestDial = errors.New("test dial")
+
+type recordingLocalAddrDialer struct {
+	addr      string
+	localAddr *net.TCPAddr
+}
+
+func (d *recordingLocalAddrDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.addr = addr
+	return nil, errTestDial
+}
+
+func (d *recordingLocalAddrDialer) DialContextFrom(ctx context.Context, network string, localAddr *net.TCPAddr, addr string) (net.Conn, error) {
+	d.addr = addr
+	d.localAddr = localAddr
+	return nil, errTestDial
+}
+
+func TestDialer(t *testing.T) {
+	t.Parallel()
+
+	t.Run("dialer func", func(t *testing.T) {
+		t.Parallel()
+
+		var got string
+		cfg := DefaultConnConfig("")
+		cfg.Dialer = DialerFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+			got = addr
+			return nil, errTestDial
+		})
+		if cfg.shardAware() {
+			t.Fatal("expected dialer without local address support not to be shard aware")
+		}
+
+		if _, err := OpenLocalPortConn("10.0.0.1", 50000, cfg); !errors.Is(err, errTestDial) {
+			t.Fatalf("got error %v, expected %v", err, errTestDial)
+		}
+		if got != "10.0.0.1:9042" {
+			t.Fatalf("got address %s, expected 10.0.0.1:9042", got)
+		}
+	})
+
+	t.Run("local address dialer", func(t *testing.T) {
+		t.Parallel()
+
+		d := new(recordingLocalAddrDialer)
+		cfg := DefaultConnConfig("")
+		cfg.Dialer = d
+		if !cfg.shardAware() {
+			t.Fatal("expected dialer with local address support to be shard aware")
+		}
+
+		if _, err := OpenLocalPortConn("10.0.0.1:19042", 50000, cfg); !errors.Is(err, errTestDial) {
+			t.Fatalf("got error %v, expected %v", err, errTestDial)
+		}
+		if d.addr != "10.0.0.1:19042" || d.localAddr == nil || d.localAddr.Port != 50000 {
+			t.Fatalf("got address %s from %v", d.addr, d.localAddr)
+		}
+
+		cfg.SNIProxy = &SNIProxyConfig{Addr: "proxy:9142"}
+		if cfg.shardAware() {
+			t.Fatal("expected connections through SNI proxy not to be shard aware")
+		}
+	})
+
+	t.Run("default dialer", func(t *testing.T) {
+		t.Parallel()
+
+		if cfg := DefaultConnConfig(""); !cfg.shardAware() {
+			t.Fatal("expected default dialer to be shard aware")
+		}
+	})
+}
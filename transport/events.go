@@ -0,0 +1,131 @@
+package transport
+
+import (
+	"net"
+	"sync"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+)
+
+// ClusterEvent is an event pushed by the cluster, it is one of TopologyChangeEvent,
+// StatusChangeEvent and SchemaChangeEvent.
+type ClusterEvent interface {
+	Type() frame.EventType
+}
+
+type TopologyChangeEvent struct {
+	Change  frame.TopologyChangeType
+	Address net.IP
+}
+
+func (TopologyChangeEvent) Type() frame.EventType {
+	return frame.TopologyChange
+}
+
+type StatusChangeEvent struct {
+	Status  frame.StatusChangeType
+	Address net.IP
+}
+
+func (StatusChangeEvent) Type() frame.EventType {
+	return frame.StatusChange
+}
+
+type SchemaChangeEvent struct {
+	Change    frame.SchemaChangeType
+	Target    frame.SchemaChangeTarget
+	Keyspace  string
+	Object    string   // Name of table, type, function or aggregate, empty for keyspace changes.
+	Arguments []string // Argument types of function or aggregate.
+}
+
+func (SchemaChangeEvent) Type() frame.EventType {
+	return frame.SchemaChange
+}
+
+// EventHandler is called sequentially for every event, it must not block
+// as it delays delivery of subsequent events.
+type EventHandler func(ev ClusterEvent)
+
+type subscriber struct {
+	id uint64
+	h  EventHandler
+}
+
+// subscribers delivers events to handlers, events that require topology or schema
+// refresh are held back until the refresh is done.
+type subscribers struct {
+	handlers []subscriber
+	nextID   uint64
+	pending  []ClusterEvent
+	mu       sync.Mutex // mu guards handlers, nextID and pending
+
+	publishMu sync.Mutex // publishMu serializes delivery
+}
+
+func (s *subscribers) subscribe(h EventHandler) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.handlers = append(s.handlers, subscriber{id: id, h: h})
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for i, v := range s.handlers {
+			if v.id == id {
+				s.handlers = append(s.handlers[:i:i], s.handlers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// postpone holds event until publishPending is called.
+func (s *subscribers) postpone(ev ClusterEvent) {
+	s.mu.Lock()
+	s.pending = append(s.pending, ev)
+	s.mu.Unlock()
+}
+
+func (s *subscribers) publishPending() {
+	s.mu.Lock()
+	events := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	s.publish(events...)
+}
+
+func (s *subscribers) publish(events ...ClusterEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	s.publishMu.Lock()
+	defer s.publishMu.Unlock()
+
+	s.mu.Lock()
+	handlers := s.handlers
+	s.mu.Unlock()
+
+	for _, ev := range events {
+		for _, v := range handlers {
+			v.h(ev)
+		}
+	}
+}
+
+func makeSchemaChangeEvent(v *SchemaChange) SchemaChangeEvent {
+	return SchemaChangeEvent{
+		Change:    v.Change,
+		Target:    v.Target,
+		Keyspace:  v.Keyspace,
+		Object:    v.Object,
+		Arguments: v.Arguments,
+	}
+}
@@ -0,0 +1,43 @@
+package transport
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+func TestSubscribers(t *testing.T) {
+	t.Parallel()
+
+	var (
+		s      subscribers
+		first  []ClusterEvent
+		second []ClusterEvent
+	)
+	unsubscribe := s.subscribe(func(ev ClusterEvent) { first = append(first, ev) })
+	s.subscribe(func(ev ClusterEvent) { second = append(second, ev) })
+
+	status := StatusChangeEvent{Status: frame.Down, Address: net.IPv4(192, 168, 100, 100)}
+	topology := TopologyChangeEvent{Change: frame.NewNode, Address: net.IPv4(192, 168, 100, 101)}
+
+	s.postpone(topology)
+	s.publish(status)
+	if len(first) != 1 || first[0].Type() != frame.StatusChange {
+		t.Fatalf("expected only status change to be delivered, got %+v", first)
+	}
+
+	s.publishPending()
+	if len(first) != 2 || first[1].Type() != frame.TopologyChange {
+		t.Fatalf("expected topology change to be delivered after refresh, got %+v", first)
+	}
+
+	unsubscribe()
+	s.publish(status)
+	if len(first) != 2 {
+		t.Fatalf("unsubscribed handler got event, got %+v", first)
+	}
+	if len(second) != 3 {
+		t.Fatalf("expected 3 events, got %+v", second)
+	}
+}
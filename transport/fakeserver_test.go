@@ -0,0 +1,29 @@
+package transport
+
+import (
+	"net"
+	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/internal/testutil"
+)
+
+// fakeHandler returns response op code and body for a request, handler may also push
+// responses to the writer directly and return op code 0 with nil body to send nothing.
+type fakeHandler = testutil.Handler
+
+// newFakeConn returns client side of connection served by h, the server stops when the connection is closed.
+func newFakeConn(t *testing.T, h fakeHandler) net.Conn {
+	t.Helper()
+	return testutil.NewConn(h)
+}
+
+// fakeSupported handles OPTIONS requests, it returns nil for other requests.
+func fakeSupported(h frame.Header) (frame.OpCode, []byte) {
+	if h.OpCode != frame.OpOptions {
+		return 0, nil
+	}
+	var b frame.Buffer
+	b.WriteStringMultiMap(frame.StringMultiMap{})
+	return frame.OpSupported, b.Bytes()
+}
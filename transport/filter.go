@@ -0,0 +1,107 @@
+package transport
+
+// HostFilter reports if node can be used in query plans.
+type HostFilter func(h HostInfo) bool
+
+// FilterPolicy removes nodes rejected by filter from plans of the wrapped policy.
+type FilterPolicy struct {
+	policy HostSelectionPolicy
+	filter HostFilter
+}
+
+var _ HostSelectionPolicy = (*FilterPolicy)(nil)
+
+func NewFilterPolicy(p HostSelectionPolicy, filter HostFilter) *FilterPolicy {
+	return &FilterPolicy{
+		policy: p,
+		filter: filter,
+	}
+}
+
+func (p *FilterPolicy) Node(qi QueryInfo, offset int) *Node {
+	for i := 0; ; i++ {
+		n := p.policy.Node(qi, i)
+		if n == nil {
+			return nil
+		}
+		if !p.filter(n.Info()) {
+			continue
+		}
+		if offset == 0 {
+			return n
+		}
+		offset--
+	}
+}
+
+func (p *FilterPolicy) LocalDC() string {
+	return localDCOf(p.policy)
+}
+
+// AllowHosts accepts only nodes with given addresses.
+func AllowHosts(addrs ...string) HostFilter {
+	m := makeSet(addrs)
+	return func(h HostInfo) bool {
+		return m[h.Addr]
+	}
+}
+
+// DenyHosts rejects nodes with given addresses.
+func DenyHosts(addrs ...string) HostFilter {
+	m := makeSet(addrs)
+	return func(h HostInfo) bool {
+		return !m[h.Addr]
+	}
+}
+
+// AllowDCs accepts only nodes from given datacenters.
+func AllowDCs(dcs ...string) HostFilter {
+	m := makeSet(dcs)
+	return func(h HostInfo) bool {
+		return m[h.Datacenter]
+	}
+}
+
+// DenyDCs rejects nodes from given datacenters.
+func DenyDCs(dcs ...string) HostFilter {
+	m := makeSet(dcs)
+	return func(h HostInfo) bool {
+		return !m[h.Datacenter]
+	}
+}
+
+// AllowRacks accepts only nodes from given racks of datacenter dc.
+func AllowRacks(dc string, racks ...string) HostFilter {
+	m := makeSet(racks)
+	return func(h HostInfo) bool {
+		return h.Datacenter == dc && m[h.Rack]
+	}
+}
+
+// DenyRacks rejects nodes from given racks of datacenter dc.
+func DenyRacks(dc string, racks ...string) HostFilter {
+	m := makeSet(racks)
+	return func(h HostInfo) bool {
+		return h.Datacenter != dc || !m[h.Rack]
+	}
+}
+
+// AllFilters accepts nodes accepted by all filters.
+func AllFilters(filters ...HostFilter) HostFilter {
+	return func(h HostInfo) bool {
+		for _, f := range filters {
+			if !f(h) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func makeSet(v []string) map[string]bool {
+	m := make(map[string]bool, len(v))
+	for _, k := range v {
+		m[k] = true
+	}
+	return m
+}
@@ -0,0 +1,73 @@
+package transport
+
+import (
+	"testing"
+)
+
+func TestFilterPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		filter   HostFilter
+		expected []string
+	}{
+		{
+			name:     "allow hosts",
+			filter:   AllowHosts("2", "7"),
+			expected: []string{"2", "7"},
+		},
+		{
+			name:     "deny hosts",
+			filter:   DenyHosts("1", "2", "3", "5", "6"),
+			expected: []string{"4", "7", "8"},
+		},
+		{
+			name:     "allow dcs",
+			filter:   AllowDCs("her"),
+			expected: []string{"5", "6", "7", "8"},
+		},
+		{
+			name:     "deny dcs",
+			filter:   DenyDCs("her"),
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:     "allow racks",
+			filter:   AllowRacks("waw", "r2"),
+			expected: []string{"3", "4"},
+		},
+		{
+			name:     "deny racks",
+			filter:   DenyRacks("her", "r3", "r4"),
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:     "all filters",
+			filter:   AllFilters(DenyRacks("waw", "r1"), DenyHosts("8")),
+			expected: []string{"3", "4", "5", "6", "7"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := mockCluster(mockTopologyTokenAwareDCAwareStrategy(), "", "waw")
+			qi := QueryInfo{topology: c.Topology()}
+			policy := NewFilterPolicy(NewTokenAwarePolicy("waw"), tc.filter)
+			for offset, addr := range tc.expected {
+				if res := policy.Node(qi, offset); res == nil || res.addr != addr {
+					t.Fatalf("offset %d: got %v but expected \"%s\"", offset, res, addr)
+				}
+			}
+			if policy.Node(qi, len(tc.expected)) != nil {
+				t.Fatalf("plan iter didn't return nil after making the whole cycle")
+			}
+			if v := policy.LocalDC(); v != "waw" {
+				t.Fatalf("got local DC %q, expected waw", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"time"
+
+	. "github.com/mmatczuk/scylla-go-driver/frame/request"
+)
+
+// heartbeatLoop sends OPTIONS request when nothing was received on connection for HeartbeatInterval,
+// if the request is not answered within HeartbeatTimeout the connection is closed.
+func (c *Conn) heartbeatLoop() {
+	interval := c.cfg.HeartbeatInterval
+	timeout := c.cfg.heartbeatTimeout()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		if Now().Sub(c.lastReceived()) < interval {
+			continue
+		}
+		if !c.heartbeat(timeout) {
+			c.cfg.logger().Warn("missed heartbeat, closing connection", "conn", c)
+			c.Close()
+			return
+		}
+	}
+}
+
+// heartbeat returns false if heartbeat was not answered within timeout or failed.
+func (c *Conn) heartbeat(timeout time.Duration) bool {
+	// Nothing was received for the whole interval while the request queue is full.
+	if c.Waiting() >= targetWaiting {
+		return false
+	}
+
+	h := MakeResponseHandler()
+	c.asyncSendRequest(&Options{}, false, false, h)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-h:
+		return resp.Err == nil
+	case <-timer.C:
+		return false
+	case <-c.done:
+		return true
+	}
+}
+
+func (c *Conn) lastReceived() time.Time {
+	return time.Unix(0, c.stats.lastReceived.Load())
+}
+
+func (cfg ConnConfig) heartbeatTimeout() time.Duration {
+	if cfg.HeartbeatTimeout > 0 {
+		return cfg.HeartbeatTimeout
+	}
+	return cfg.HeartbeatInterval
+}
@@ -0,0 +1,79 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"go.uber.org/atomic"
+)
+
+// fakeHeartbeatServer answers startup and first answered OPTIONS requests,
+// later OPTIONS requests are ignored as if the connection was stuck.
+func fakeHeartbeatServer(answered int32) (fakeHandler, *atomic.Int32) {
+	options := atomic.NewInt32(0)
+	return func(h frame.Header, body []byte) (frame.OpCode, []byte) {
+		switch h.OpCode {
+		case frame.OpOptions:
+			if options.Inc() > answered {
+				return 0, nil
+			}
+			return fakeSupported(h)
+		case frame.OpStartup:
+			return frame.OpReady, nil
+		default:
+			return 0, nil
+		}
+	}, options
+}
+
+func TestConnHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	cfg := DefaultConnConfig("")
+	cfg.HeartbeatInterval = 10 * time.Millisecond
+	cfg.HeartbeatTimeout = 50 * time.Millisecond
+
+	t.Run("answered", func(t *testing.T) {
+		t.Parallel()
+
+		h, options := fakeHeartbeatServer(1000)
+		conn, err := WrapConn(newFakeConn(t, h), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed := make(chan struct{})
+		conn.setOnClose(func(*Conn) { close(closed) })
+
+		select {
+		case <-closed:
+			t.Fatal("connection closed")
+		case <-time.After(200 * time.Millisecond):
+		}
+		conn.Close()
+
+		// First OPTIONS request is sent by init.
+		if v := options.Load(); v < 3 {
+			t.Fatalf("got %d OPTIONS requests, expected heartbeats", v)
+		}
+	})
+
+	t.Run("missed", func(t *testing.T) {
+		t.Parallel()
+
+		h, _ := fakeHeartbeatServer(1)
+		conn, err := WrapConn(newFakeConn(t, h), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		closed := make(chan struct{})
+		conn.setOnClose(func(*Conn) { close(closed) })
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			conn.Close()
+			t.Fatal("connection not closed after missed heartbeat")
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package transport
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// LatencyTracker is implemented by host selection policies that need latencies of completed requests.
+type LatencyTracker interface {
+	TrackLatency(n *Node, latency time.Duration)
+}
+
+type LatencyAwareConfig struct {
+	// ExclusionThreshold is how many times node average latency may exceed the best
+	// average latency among the plan nodes before the node is moved to the end of the plan.
+	ExclusionThreshold float64
+	// Scale controls how fast old measurements decay, measurement older
+	// by Scale weights 1/e of the latest one.
+	Scale time.Duration
+	// RetryPeriod is the time after which measurements of a node that got no traffic become stale,
+	// and the node is probed again with regular traffic.
+	RetryPeriod time.Duration
+	// MinMeasured is the number of measurements required before node can be penalized.
+	MinMeasured int
+}
+
+func DefaultLatencyAwareConfig() LatencyAwareConfig {
+	return LatencyAwareConfig{
+		ExclusionThreshold: 2,
+		Scale:              100 * time.Millisecond,
+		RetryPeriod:        10 * time.Second,
+		MinMeasured:        50,
+	}
+}
+
+// LatencyAwarePolicy wraps a policy and moves nodes which are much slower than
+// the fastest node of the plan to the end of the plan.
+type LatencyAwarePolicy struct {
+	policy HostSelectionPolicy
+	cfg    LatencyAwareConfig
+
+	latency  map[string]*nodeLatency
+	topology *topology    // topology latency map was last pruned for
+	mu       sync.RWMutex // mu guards latency and topology
+}
+
+var (
+	_ HostSelectionPolicy = (*LatencyAwarePolicy)(nil)
+	_ LatencyTracker      = (*LatencyAwarePolicy)(nil)
+)
+
+func NewLatencyAwarePolicy(p HostSelectionPolicy, cfg LatencyAwareConfig) *LatencyAwarePolicy {
+	return &LatencyAwarePolicy{
+		policy:  p,
+		cfg:     cfg,
+		latency: make(map[string]*nodeLatency),
+	}
+}
+
+// nodeLatency is exponentially decaying average of node latencies.
+type nodeLatency struct {
+	avg        float64 // in nanoseconds
+	measured   int
+	lastUpdate time.Time
+	mu         sync.Mutex // mu guards avg, measured and lastUpdate
+}
+
+func (l *nodeLatency) add(latency time.Duration, now time.Time, scale time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.measured == 0 {
+		l.avg = float64(latency)
+	} else {
+		elapsed := float64(now.Sub(l.lastUpdate))
+		if elapsed < 0 {
+			elapsed = 0
+		}
+		alpha := 1 - math.Exp(-elapsed/float64(scale))
+		l.avg += alpha * (float64(latency) - l.avg)
+	}
+	l.measured++
+	l.lastUpdate = now
+}
+
+// get returns average latency, ok is false if there are not enough fresh measurements.
+func (l *nodeLatency) get(now time.Time, cfg LatencyAwareConfig) (avg float64, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.measured < cfg.MinMeasured || now.Sub(l.lastUpdate) > cfg.RetryPeriod {
+		return 0, false
+	}
+	return l.avg, true
+}
+
+func (p *LatencyAwarePolicy) TrackLatency(n *Node, latency time.Duration) {
+	p.mu.RLock()
+	l, ok := p.latency[n.addr]
+	p.mu.RUnlock()
+
+	if !ok {
+		p.mu.Lock()
+		if l, ok = p.latency[n.addr]; !ok {
+			l = new(nodeLatency)
+			p.latency[n.addr] = l
+		}
+		p.mu.Unlock()
+	}
+
+	l.add(latency, Now(), p.cfg.Scale)
+}
+
+// Node reorders plan of the wrapped policy, the order is computed once per query.
+func (p *LatencyAwarePolicy) Node(qi QueryInfo, offset int) *Node {
+	p.prune(qi.topology)
+	return qi.planNode(p, offset, func() []*Node {
+		return p.plan(qi)
+	})
+}
+
+func (p *LatencyAwarePolicy) plan(qi QueryInfo) []*Node {
+	var plan []*Node
+	for i := 0; ; i++ {
+		n := p.policy.Node(qi, i)
+		if n == nil {
+			break
+		}
+		plan = append(plan, n)
+	}
+
+	now := Now()
+	avg := make([]float64, len(plan))
+	best := math.Inf(1)
+	p.mu.RLock()
+	for i, n := range plan {
+		avg[i] = math.NaN()
+		if l, ok := p.latency[n.addr]; ok {
+			if v, ok := l.get(now, p.cfg); ok {
+				avg[i] = v
+				best = math.Min(best, v)
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	// Nodes without enough fresh measurements are never penalized, so that they get probed.
+	penalized := func(i int) bool {
+		return !math.IsNaN(avg[i]) && avg[i] > best*p.cfg.ExclusionThreshold
+	}
+	res := make([]*Node, 0, len(plan))
+	for i := range plan {
+		if !penalized(i) {
+			res = append(res, plan[i])
+		}
+	}
+	for i := range plan {
+		if penalized(i) {
+			res = append(res, plan[i])
+		}
+	}
+	return res
+}
+
+// prune removes latencies of nodes which are not part of t, it's done once per topology change.
+func (p *LatencyAwarePolicy) prune(t *topology) {
+	if t == nil {
+		return
+	}
+	p.mu.RLock()
+	pruned := p.topology == t
+	p.mu.RUnlock()
+	if pruned {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topology = t
+	for addr := range p.latency {
+		if _, ok := t.peers[addr]; !ok {
+			delete(p.latency, addr)
+		}
+	}
+}
+
+func (p *LatencyAwarePolicy) LocalDC() string {
+	return localDCOf(p.policy)
+}
@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"testing"
+	"time"
+)
+
+// staticPolicy returns nodes in the given order.
+type staticPolicy []*Node
+
+func (p staticPolicy) Node(_ QueryInfo, offset int) *Node {
+	if offset < len(p) {
+		return p[offset]
+	}
+	return nil
+}
+
+func TestLatencyAwarePolicy(t *testing.T) {
+	t.Parallel()
+
+	nodes := markUp([]*Node{{addr: "1"}, {addr: "2"}, {addr: "3"}})
+	cfg := LatencyAwareConfig{
+		ExclusionThreshold: 2,
+		Scale:              100 * time.Millisecond,
+		RetryPeriod:        time.Minute,
+		MinMeasured:        3,
+	}
+
+	testCases := []struct {
+		name     string
+		latency  map[string]time.Duration
+		measured int
+		expected []string
+	}{
+		{
+			name:     "no measurements",
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "not enough measurements",
+			latency:  map[string]time.Duration{"1": 100 * time.Millisecond, "2": time.Millisecond},
+			measured: 2,
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "slow node is moved to the end",
+			latency:  map[string]time.Duration{"1": 100 * time.Millisecond, "2": time.Millisecond, "3": time.Millisecond},
+			measured: 3,
+			expected: []string{"2", "3", "1"},
+		},
+		{
+			name:     "below threshold",
+			latency:  map[string]time.Duration{"1": 3 * time.Millisecond, "2": 2 * time.Millisecond},
+			measured: 3,
+			expected: []string{"1", "2", "3"},
+		},
+		{
+			name:     "unmeasured node is not penalized",
+			latency:  map[string]time.Duration{"1": 100 * time.Millisecond, "3": 10 * time.Millisecond},
+			measured: 3,
+			expected: []string{"2", "3", "1"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewLatencyAwarePolicy(staticPolicy(nodes), cfg)
+			for _, n := range nodes {
+				if d, ok := tc.latency[n.addr]; ok {
+					for j := 0; j < tc.measured; j++ {
+						p.TrackLatency(n, d)
+					}
+				}
+			}
+
+			for j, e := range tc.expected {
+				if n := p.Node(QueryInfo{}, j); n == nil || n.addr != e {
+					t.Fatalf("offset %d: got %v, expected node %s", j, n, e)
+				}
+			}
+			if n := p.Node(QueryInfo{}, len(tc.expected)); n != nil {
+				t.Fatalf("expected end of plan, got node %s", n.addr)
+			}
+		})
+	}
+}
+
+func TestLatencyAwarePolicyPlanAndPrune(t *testing.T) {
+	t.Parallel()
+
+	nodes := markUp([]*Node{{addr: "1"}, {addr: "2"}, {addr: "3"}})
+	cfg := LatencyAwareConfig{
+		ExclusionThreshold: 2,
+		Scale:              100 * time.Millisecond,
+		RetryPeriod:        time.Minute,
+		MinMeasured:        1,
+	}
+	p := NewLatencyAwarePolicy(staticPolicy(nodes), cfg)
+	p.TrackLatency(nodes[1], time.Millisecond)
+	p.TrackLatency(nodes[2], time.Millisecond)
+
+	top := &topology{peers: peerMap{"1": nodes[0], "2": nodes[1]}}
+	qi := QueryInfo{topology: top, plans: new(planCache)}
+	if n := p.Node(qi, 0); n == nil || n.addr != "1" {
+		t.Fatalf("got %v, expected node 1", n)
+	}
+	if _, ok := p.latency["3"]; ok || len(p.latency) != 1 {
+		t.Fatalf("expected latency of node removed from topology to be pruned, got %v", p.latency)
+	}
+
+	// Order is computed once per query, node 1 is unmeasured when the plan is built.
+	p.TrackLatency(nodes[0], time.Second)
+	if n := p.Node(qi, 0); n == nil || n.addr != "1" {
+		t.Fatalf("got %v, expected plan not to change during query", n)
+	}
+	if n := p.Node(QueryInfo{topology: top, plans: new(planCache)}, 0); n == nil || n.addr != "2" {
+		t.Fatalf("got %v, expected slow node to be moved to the end of a new plan", n)
+	}
+}
+
+func TestNodeLatency(t *testing.T) {
+	t.Parallel()
+
+	cfg := LatencyAwareConfig{
+		Scale:       time.Second,
+		RetryPeriod: 10 * time.Second,
+		MinMeasured: 1,
+	}
+	start := time.Unix(0, 0)
+
+	var l nodeLatency
+	l.add(100*time.Millisecond, start, cfg.Scale)
+	if v, ok := l.get(start, cfg); !ok || v != float64(100*time.Millisecond) {
+		t.Fatalf("got %v %v, expected first measurement", v, ok)
+	}
+
+	l.add(0, start, cfg.Scale)
+	if v, _ := l.get(start, cfg); v != float64(100*time.Millisecond) {
+		t.Fatalf("measurement with no elapsed time should not change average, got %v", v)
+	}
+
+	l.add(0, start.Add(time.Second), cfg.Scale)
+	v, _ := l.get(start.Add(time.Second), cfg)
+	if v <= 0 || v >= float64(50*time.Millisecond) {
+		t.Fatalf("expected average to decay below 50ms, got %v", time.Duration(v))
+	}
+
+	if _, ok := l.get(start.Add(time.Minute), cfg); ok {
+		t.Fatal("expected stale measurements to be ignored")
+	}
+}
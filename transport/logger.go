@@ -0,0 +1,19 @@
+package transport
+
+import (
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+type (
+	Logger    = frame.Logger
+	StdLogger = frame.StdLogger
+	NopLogger = frame.NopLogger
+)
+
+// logger returns configured Logger or StdLogger if it's not set.
+func (cfg ConnConfig) logger() Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return StdLogger{}
+}
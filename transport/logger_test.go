@@ -0,0 +1,63 @@
+package transport
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+// recordingLogger records messages in level: msg format.
+type recordingLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *recordingLogger) record(level, msg string, keyvals []interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.msgs = append(l.msgs, fmt.Sprintf("%s: %s %v", level, msg, keyvals))
+}
+
+func (l *recordingLogger) Debug(msg string, keyvals ...interface{}) { l.record("debug", msg, keyvals) }
+func (l *recordingLogger) Info(msg string, keyvals ...interface{})  { l.record("info", msg, keyvals) }
+func (l *recordingLogger) Warn(msg string, keyvals ...interface{})  { l.record("warn", msg, keyvals) }
+func (l *recordingLogger) Error(msg string, keyvals ...interface{}) { l.record("error", msg, keyvals) }
+
+func (l *recordingLogger) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, m := range l.msgs {
+		if strings.Contains(m, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestConnUnknownOpCode(t *testing.T) {
+	t.Parallel()
+
+	const unknownOpCode frame.OpCode = 0x7F
+	h := func(h frame.Header, body []byte) (frame.OpCode, []byte) {
+		return unknownOpCode, nil
+	}
+
+	logger := new(recordingLogger)
+	cfg := DefaultConnConfig("")
+	cfg.Logger = logger
+
+	conn, err := WrapConn(newFakeConn(t, h), cfg)
+	if conn != nil {
+		conn.Close()
+		conn.wait()
+	}
+	if err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Fatalf("got error %v, expected connection closed error", err)
+	}
+	if !logger.contains("warn: receive failed, closing connection") {
+		t.Fatalf("got messages %v", logger.msgs)
+	}
+}
@@ -1,6 +1,10 @@
 package transport
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
 	"github.com/mmatczuk/scylla-go-driver/frame"
 	"go.uber.org/atomic"
 )
@@ -25,8 +29,37 @@ func (n *Node) Status() bool {
 	return n.status.Load()
 }
 
-func (n *Node) setStatus(v bool) {
-	n.status.Store(v)
+// setStatus returns true if status has changed.
+func (n *Node) setStatus(v bool) bool {
+	return n.status.Swap(v) != v
+}
+
+// HostInfo is a snapshot of node state, it's not updated when the node changes.
+type HostInfo struct {
+	HostID     frame.UUID
+	Addr       string
+	Datacenter string
+	Rack       string
+	Up         bool
+}
+
+func (n *Node) Info() HostInfo {
+	return HostInfo{
+		HostID:     n.hostID,
+		Addr:       n.addr,
+		Datacenter: n.datacenter,
+		Rack:       n.rack,
+		Up:         n.Status(),
+	}
+}
+
+// HostListener is notified about nodes added to or removed from topology and about their status changes.
+// Callbacks are called sequentially from the cluster loop or the control connection reader, they must not block.
+type HostListener interface {
+	OnAdd(h HostInfo)
+	OnRemove(h HostInfo)
+	OnUp(h HostInfo)
+	OnDown(h HostInfo)
 }
 
 func (n *Node) LeastBusyConn() *Conn {
@@ -37,6 +70,80 @@ func (n *Node) Conn(token Token) *Conn {
 	return n.pool.Conn(token)
 }
 
+// IsAvailable returns false if node is down or its circuit breaker is open.
+func (n *Node) IsAvailable() bool {
+	if !n.Status() {
+		return false
+	}
+	if n.pool == nil {
+		return true
+	}
+	return n.pool.breaker.allow()
+}
+
+// BreakerState returns state of node circuit breaker.
+func (n *Node) BreakerState() BreakerState {
+	if n.pool == nil {
+		return BreakerClosed
+	}
+	return n.pool.breaker.State()
+}
+
+// ReportResult feeds node circuit breaker with result of a request sent to the node.
+func (n *Node) ReportResult(err error) {
+	if n.pool == nil {
+		return
+	}
+	if isNodeFailure(err) {
+		n.pool.breaker.failure()
+	} else {
+		n.pool.breaker.success()
+	}
+}
+
+// Waiting returns number of requests queued or in flight on all connections to the node.
+func (n *Node) Waiting() int {
+	if n.pool == nil {
+		return 0
+	}
+	return n.pool.Waiting()
+}
+
+var (
+	ErrNoSuchHost       = errors.New("no such host")
+	ErrHostNotConnected = errors.New("host not connected")
+)
+
+// ShardConn returns connection to given shard of the node.
+func (n *Node) ShardConn(shard int) (*Conn, error) {
+	if n.pool == nil {
+		return nil, fmt.Errorf("%w: %s", ErrHostNotConnected, n.addr)
+	}
+	if shard < 0 || shard >= len(n.pool.conns) {
+		return nil, fmt.Errorf("node %s has %d shards, got shard %d", n.addr, len(n.pool.conns), shard)
+	}
+	conn := n.pool.loadConn(shard)
+	if conn == nil {
+		return nil, fmt.Errorf("%w: %s shard %d", ErrHostNotConnected, n.addr, shard)
+	}
+	return conn, nil
+}
+
+// formatUUID returns u in the canonical xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx format.
+func formatUUID(u frame.UUID) string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
 type RingEntry struct {
 	node           *Node
 	token          Token
@@ -2,8 +2,9 @@ package transport
 
 import (
 	"fmt"
-	"log"
 	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
 )
 
 var Now = time.Now
@@ -46,23 +47,135 @@ type ConnectEvent struct {
 	Err error
 }
 
+// StreamsExhaustedEvent is reported when a request can't get a stream ID because all are in use.
+type StreamsExhaustedEvent struct {
+	ConnEvent
+
+	// Waiting is the number of requests waiting for a stream ID.
+	Waiting int
+	// Rejected is set if the request failed because the wait queue was full or it waited too long.
+	Rejected bool
+}
+
+// BreakerEvent is reported when node circuit breaker changes state.
+type BreakerEvent struct {
+	Addr string
+	From BreakerState
+	To   BreakerState
+}
+
+func (ev BreakerEvent) String() string {
+	return fmt.Sprintf("[addr=%s breaker=%s->%s]", ev.Addr, ev.From, ev.To)
+}
+
+// QueryEvent describes a single attempt of running a statement on a node,
+// every result page fetched by an iterator is reported as a separate request.
+type QueryEvent struct {
+	Statement   string
+	Host        HostInfo
+	Shard       int
+	Consistency frame.Consistency
+	// Attempt is the number of the attempt starting from 1, requests are not retried
+	// nor executed speculatively yet so it's always 1.
+	Attempt int
+	// Page is the number of the fetched result page starting from 1.
+	Page int
+}
+
+// QueryEndEvent is reported when attempt finishes.
+type QueryEndEvent struct {
+	QueryEvent
+
+	Latency time.Duration
+	// Rows is the number of rows returned.
+	Rows int
+	// Err is the attempt error (if any).
+	Err error
+}
+
+// QueryObserver is notified about start and end of every request attempt, callbacks must not block.
+type QueryObserver interface {
+	OnQueryStart(ev QueryEvent)
+	OnQueryEnd(ev QueryEndEvent)
+}
+
+// TopologyRefreshEvent is reported after cluster topology refresh.
+type TopologyRefreshEvent struct {
+	span
+
+	// Err is the refresh error (if any).
+	Err error
+}
+
+// ControlReopenEvent is reported after an attempt to reopen control connection.
+type ControlReopenEvent struct {
+	span
+
+	// Err is the connection error (if any).
+	Err error
+}
+
+// ClusterObserver is notified about cluster maintenance done by the driver, callbacks must not block.
+type ClusterObserver interface {
+	OnTopologyRefresh(ev TopologyRefreshEvent)
+	OnControlReopen(ev ControlReopenEvent)
+}
+
 type ConnObserver interface {
 	OnConnect(ev ConnectEvent)
 	OnPickReplacedWithLessBusyConn(ev ConnEvent)
 }
 
-type LoggingConnObserver struct{}
+// StreamsObserver is an optional interface of ConnObserver, if implemented it's notified
+// when requests wait for stream IDs.
+type StreamsObserver interface {
+	OnStreamsExhausted(ev StreamsExhaustedEvent)
+}
+
+// BreakerObserver is an optional interface of ConnObserver, if implemented it's notified
+// when node circuit breaker changes state.
+type BreakerObserver interface {
+	OnBreakerStateChange(ev BreakerEvent)
+}
+
+// LoggingConnObserver reports events to Logger, if Logger is not set StdLogger is used.
+type LoggingConnObserver struct {
+	Logger Logger
+}
+
+var (
+	_ ConnObserver    = LoggingConnObserver{}
+	_ StreamsObserver = LoggingConnObserver{}
+	_ BreakerObserver = LoggingConnObserver{}
+)
 
-var _ ConnObserver = LoggingConnObserver{}
+func (o LoggingConnObserver) logger() Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return StdLogger{}
+}
 
 func (o LoggingConnObserver) OnConnect(ev ConnectEvent) {
 	if ev.Err != nil {
-		log.Printf("%s failed to open connection after %s: %s", ev, ev.Duration(), ev.Err)
+		o.logger().Warn("failed to open connection", "addr", ev.Addr, "shard", ev.Shard, "duration", ev.Duration(), "error", ev.Err)
 	} else {
-		log.Printf("%s connected in %s", ev, ev.Duration())
+		o.logger().Info("connected", "addr", ev.Addr, "shard", ev.Shard, "duration", ev.Duration())
 	}
 }
 
 func (o LoggingConnObserver) OnPickReplacedWithLessBusyConn(ev ConnEvent) {
-	log.Printf("%s pick replaced with less busy conn", ev)
+	o.logger().Debug("pick replaced with less busy conn", "addr", ev.Addr, "shard", ev.Shard)
+}
+
+func (o LoggingConnObserver) OnStreamsExhausted(ev StreamsExhaustedEvent) {
+	if ev.Rejected {
+		o.logger().Warn("all stream IDs are busy, request rejected", "addr", ev.Addr, "shard", ev.Shard, "waiting", ev.Waiting)
+	} else {
+		o.logger().Debug("all stream IDs are busy", "addr", ev.Addr, "shard", ev.Shard, "waiting", ev.Waiting)
+	}
+}
+
+func (o LoggingConnObserver) OnBreakerStateChange(ev BreakerEvent) {
+	o.logger().Warn("circuit breaker state changed", "addr", ev.Addr, "from", ev.From, "to", ev.To)
 }
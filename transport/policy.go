@@ -1,7 +1,6 @@
 package transport
 
 import (
-	"log"
 	"sort"
 )
 
@@ -11,57 +10,310 @@ type HostSelectionPolicy interface {
 	Node(QueryInfo, int) *Node
 }
 
+// localDCPolicy is implemented by policies which prefer nodes from local datacenter.
+type localDCPolicy interface {
+	LocalDC() string
+}
+
+// localDCOf returns local datacenter of p or empty string if p is not datacenter aware.
+func localDCOf(p HostSelectionPolicy) string {
+	if v, ok := p.(localDCPolicy); ok {
+		return v.LocalDC()
+	}
+	return ""
+}
+
+// PolicyOption modifies how a policy builds query plans.
+type PolicyOption func(*policyOptions)
+
+type policyOptions struct {
+	shuffleReplicas bool
+	maxRemoteNodes  int // negative means no limit
+}
+
+func makePolicyOptions(opts []PolicyOption) policyOptions {
+	o := policyOptions{maxRemoteNodes: -1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// ShuffleReplicas makes token aware plans try replicas in pseudo random order instead of rotating them.
+// Order is fixed for a single query so that the whole plan is consistent.
+func ShuffleReplicas() PolicyOption {
+	return func(o *policyOptions) {
+		o.shuffleReplicas = true
+	}
+}
+
+// MaxRemoteNodes limits the number of remote datacenter nodes used by a single query,
+// zero disables failover to remote datacenters.
+func MaxRemoteNodes(n int) PolicyOption {
+	return func(o *policyOptions) {
+		o.maxRemoteNodes = n
+	}
+}
+
+// RoundRobinPolicy goes through all nodes ignoring token and datacenters.
+type RoundRobinPolicy struct{}
+
+func NewRoundRobinPolicy() *RoundRobinPolicy {
+	return &RoundRobinPolicy{}
+}
+
+// Node skips nodes that are not available, see Node.IsAvailable.
+func (p *RoundRobinPolicy) Node(qi QueryInfo, offset int) *Node {
+	return pickAvailable(qi, &offset, qi.topology.nodes, false, -1)
+}
+
+// DCAwareRoundRobinPolicy goes through local datacenter nodes and then through remote nodes ignoring token.
+type DCAwareRoundRobinPolicy struct {
+	localDC string
+	opts    policyOptions
+}
+
+func NewDCAwareRoundRobinPolicy(localDC string, opts ...PolicyOption) *DCAwareRoundRobinPolicy {
+	return &DCAwareRoundRobinPolicy{
+		localDC: localDC,
+		opts:    makePolicyOptions(opts),
+	}
+}
+
+func (p *DCAwareRoundRobinPolicy) LocalDC() string {
+	return p.localDC
+}
+
+// Node skips nodes that are not available, see Node.IsAvailable.
+func (p *DCAwareRoundRobinPolicy) Node(qi QueryInfo, offset int) *Node {
+	pi := qi.topology.policyInfo
+	return p.opts.pick(qi, offset, pi.remoteNodes, pi.localNodes)
+}
+
 type TokenAwarePolicy struct {
 	localDC string
+	opts    policyOptions
 }
 
-func NewTokenAwarePolicy(localDC string) *TokenAwarePolicy {
-	return &TokenAwarePolicy{localDC: localDC}
+func NewTokenAwarePolicy(localDC string, opts ...PolicyOption) *TokenAwarePolicy {
+	return &TokenAwarePolicy{
+		localDC: localDC,
+		opts:    makePolicyOptions(opts),
+	}
+}
+
+func (p *TokenAwarePolicy) LocalDC() string {
+	return p.localDC
 }
 
+// Node skips nodes that are down, nodes with open circuit breaker are put at the end of the plan.
 func (p *TokenAwarePolicy) Node(qi QueryInfo, offset int) *Node {
-	if p.localDC == "" {
-		var replicas []*Node
-		pi := qi.topology.policyInfo
-		if qi.tokenAware {
-			pos := pi.ring.tokenLowerBound(qi.token)
-			replicas = pi.ring[pos].localReplicas
-		} else {
-			// Fallback to round robin on all nodes.
-			replicas = pi.localNodes
-		}
+	return qi.planNode(p, offset, func() []*Node {
+		local, remote := candidates(qi, p.localDC)
+		return p.opts.plan(qi, remote, local)
+	})
+}
 
-		if offset >= len(replicas) {
-			return nil
-		}
+// RackAwarePolicy is a token aware policy which prefers replicas in the local rack,
+// then replicas in the local datacenter and then replicas in remote datacenters.
+// If token is unknown it falls back to rack aware round robin on all nodes.
+type RackAwarePolicy struct {
+	localDC   string
+	localRack string
+	opts      policyOptions
+}
 
-		idx := (qi.offset + uint64(offset)) % uint64(len(replicas))
-		return replicas[idx]
+func NewRackAwarePolicy(localDC, localRack string, opts ...PolicyOption) *RackAwarePolicy {
+	return &RackAwarePolicy{
+		localDC:   localDC,
+		localRack: localRack,
+		opts:      makePolicyOptions(opts),
 	}
+}
+
+func (p *RackAwarePolicy) LocalDC() string {
+	return p.localDC
+}
+
+// Node skips nodes that are down, nodes with open circuit breaker are put at the end of the plan.
+func (p *RackAwarePolicy) Node(qi QueryInfo, offset int) *Node {
+	return qi.planNode(p, offset, func() []*Node {
+		local, remote := candidates(qi, p.localDC)
+
+		rack := make([]*Node, 0, len(local))
+		other := make([]*Node, 0, len(local))
+		for _, n := range local {
+			if n.rack == p.localRack {
+				rack = append(rack, n)
+			} else {
+				other = append(other, n)
+			}
+		}
+
+		return p.opts.plan(qi, remote, rack, other)
+	})
+}
 
-	var local, remote []*Node
+// candidates returns replicas of the token or all nodes if the query is not token aware.
+// Remote nodes are returned only if localDC is set.
+func candidates(qi QueryInfo, localDC string) (local, remote []*Node) {
 	pi := qi.topology.policyInfo
 	if qi.tokenAware {
 		pos := pi.ring.tokenLowerBound(qi.token)
 		local = pi.ring[pos].localReplicas
 		remote = pi.ring[pos].remoteReplicas
 	} else {
-		// Fallback to DC aware round robin on all nodes.
+		// Fallback to (DC aware) round robin on all nodes.
 		local = pi.localNodes
 		remote = pi.remoteNodes
 	}
+	if localDC == "" {
+		remote = nil
+	}
+	return local, remote
+}
+
+// pick returns offset-th available node going through local groups in order,
+// and then through at most maxRemoteNodes available remote nodes.
+func (o policyOptions) pick(qi QueryInfo, offset int, remote []*Node, local ...[]*Node) *Node {
+	shuffle := o.shuffleReplicas && qi.tokenAware
+	for _, g := range local {
+		if n := pickAvailable(qi, &offset, g, shuffle, -1); n != nil {
+			return n
+		}
+	}
+	return pickAvailable(qi, &offset, remote, shuffle, o.maxRemoteNodes)
+}
 
-	if offset < len(local) {
-		idx := (qi.offset + uint64(offset)) % uint64(len(local))
-		return local[idx]
-	} else if offset < len(local)+len(remote) {
-		idx := (qi.offset + uint64(offset) - uint64(len(local))) % uint64(len(remote))
-		return remote[idx]
+// plan returns available nodes going through local groups in order, and then through at most
+// maxRemoteNodes available remote nodes. Nodes with open circuit breaker are put at the end
+// of the plan, so that the query can be sent even if breakers of all candidates are open,
+// nodes which are down are skipped.
+func (o policyOptions) plan(qi QueryInfo, remote []*Node, local ...[]*Node) []*Node {
+	shuffle := o.shuffleReplicas && qi.tokenAware
+	var res, open []*Node
+	for _, g := range local {
+		res, open = appendAvailable(res, open, qi, g, shuffle, -1)
+	}
+	res, open = appendAvailable(res, open, qi, remote, shuffle, o.maxRemoteNodes)
+	return append(res, open...)
+}
+
+// appendAvailable appends available nodes of g rotated or shuffled by query offset to res,
+// and nodes which are up but have open circuit breaker to open. At most limit available nodes
+// are considered, negative limit means no limit.
+func appendAvailable(res, open []*Node, qi QueryInfo, g []*Node, shuffle bool, limit int) (r, o []*Node) {
+	if shuffle {
+		g = shuffled(g, qi.offset)
+	}
+	for i := range g {
+		if limit == 0 {
+			break
+		}
+		var n *Node
+		if shuffle {
+			n = g[i]
+		} else {
+			n = g[(qi.offset+uint64(i))%uint64(len(g))]
+		}
+		if !n.Status() {
+			continue
+		}
+		if !n.IsAvailable() {
+			open = append(open, n)
+			continue
+		}
+		res = append(res, n)
+		limit--
+	}
+	return res, open
+}
+
+// pickAvailable returns offset-th available node of g rotated or shuffled by query offset,
+// offset is decreased by the number of available nodes skipped. At most limit available nodes
+// are considered, negative limit means no limit.
+func pickAvailable(qi QueryInfo, offset *int, g []*Node, shuffle bool, limit int) *Node {
+	if shuffle {
+		g = shuffled(g, qi.offset)
+	}
+	for i := range g {
+		if limit == 0 {
+			return nil
+		}
+		var n *Node
+		if shuffle {
+			n = g[i]
+		} else {
+			n = g[(qi.offset+uint64(i))%uint64(len(g))]
+		}
+		if !n.IsAvailable() {
+			continue
+		}
+		if *offset == 0 {
+			return n
+		}
+		*offset--
+		limit--
 	}
 
 	return nil
 }
 
+// shuffled returns a copy of nodes permuted with Fisher-Yates shuffle seeded with seed,
+// the same seed gives the same permutation.
+func shuffled(nodes []*Node, seed uint64) []*Node {
+	res := make([]*Node, len(nodes))
+	copy(res, nodes)
+	for i := len(res) - 1; i > 0; i-- {
+		seed = splitmix64(seed)
+		j := int(seed % uint64(i+1))
+		res[i], res[j] = res[j], res[i]
+	}
+	return res
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// planCache holds plans built for a single query, so that policies build them once
+// instead of for every node of the plan.
+type planCache struct {
+	plans []cachedPlan
+}
+
+type cachedPlan struct {
+	policy HostSelectionPolicy
+	nodes  []*Node
+}
+
+// planNode returns offset-th node of the plan of policy p, the plan is built with build
+// on the first call and cached in qi.
+func (qi QueryInfo) planNode(p HostSelectionPolicy, offset int, build func() []*Node) *Node {
+	var nodes []*Node
+	if qi.plans != nil {
+		for _, v := range qi.plans.plans {
+			if v.policy == p {
+				nodes = v.nodes
+				break
+			}
+		}
+	}
+	if nodes == nil {
+		nodes = build()
+		if qi.plans != nil {
+			qi.plans.plans = append(qi.plans.plans, cachedPlan{policy: p, nodes: nodes})
+		}
+	}
+	if offset < 0 || offset >= len(nodes) {
+		return nil
+	}
+	return nodes[offset]
+}
+
 type policyInfo struct {
 	ring Ring
 
@@ -69,24 +321,32 @@ type policyInfo struct {
 	remoteNodes []*Node
 }
 
-func (pi *policyInfo) Preprocess(t *topology, ks keyspace) {
+// Preprocess computes node lists and ring replicas, it returns false if keyspace strategy
+// is unknown and only round robin node lists are available.
+func (pi *policyInfo) Preprocess(t *topology, ks keyspace) bool {
+	// Node lists are used for round robin when query is not token aware.
+	if t.localDC == "" {
+		pi.preprocessRoundRobinStrategy(t)
+	} else {
+		pi.preprocessDCAwareRoundRobinStrategy(t)
+	}
+
 	switch ks.strategy.class {
-	case simpleStrategy, localStrategy:
+	case simpleStrategy:
 		pi.preprocessSimpleStrategy(t, ks.strategy)
+	case localStrategy:
+		pi.preprocessLocalStrategy()
+	case everywhereStrategy:
+		pi.preprocessEverywhereStrategy(t)
 	case networkTopologyStrategy:
 		pi.preprocessNetworkTopologyStrategy(t, ks.strategy)
 	default:
-		log.Println("policyInfo: unknown strategy, defaulting to round robin")
-		if t.localDC == "" {
-			pi.preprocessRoundRobinStrategy(t)
-		} else {
-			pi.preprocessDCAwareRoundRobinStrategy(t)
-		}
+		return false
 	}
+	return true
 }
 
 func (pi *policyInfo) preprocessSimpleStrategy(t *topology, stg strategy) {
-	pi.localNodes = t.nodes
 	sort.Sort(pi.ring)
 	trie := trieRoot()
 	for i := range pi.ring {
@@ -121,6 +381,51 @@ func (pi *policyInfo) preprocessSimpleStrategy(t *topology, stg strategy) {
 	}
 }
 
+// preprocessLocalStrategy makes every node a replica, data of keyspaces with local strategy
+// is stored on each node separately so the query is served by the coordinator itself.
+// Replicas are the same as round robin nodes.
+func (pi *policyInfo) preprocessLocalStrategy() {
+	for i := range pi.ring {
+		pi.ring[i].localReplicas = pi.localNodes
+		pi.ring[i].remoteReplicas = pi.remoteNodes
+	}
+}
+
+// preprocessEverywhereStrategy makes every node a replica, starting from the token owner.
+func (pi *policyInfo) preprocessEverywhereStrategy(t *topology) {
+	sort.Sort(pi.ring)
+	trie := trieRoot()
+	// seen is reused for all ring entries, walk stops once every node was seen.
+	seen := make(map[string]struct{}, len(t.nodes))
+	for i := range pi.ring {
+		rit := replicaIter{
+			ring:    pi.ring,
+			offset:  i,
+			fetched: 0,
+		}
+
+		for k := range seen {
+			delete(seen, k)
+		}
+		local := &trie
+		remote := &trie
+		for n := rit.Next(); n != nil && len(seen) < len(t.nodes); n = rit.Next() {
+			if _, ok := seen[n.addr]; ok {
+				continue
+			}
+			seen[n.addr] = struct{}{}
+			if t.localDC == "" || n.datacenter == t.localDC {
+				local = local.Next(n)
+			} else {
+				remote = remote.Next(n)
+			}
+		}
+
+		pi.ring[i].localReplicas = local.Path()
+		pi.ring[i].remoteReplicas = remote.Path()
+	}
+}
+
 func (pi *policyInfo) preprocessRoundRobinStrategy(t *topology) {
 	pi.localNodes = t.nodes
 	pi.remoteNodes = nil
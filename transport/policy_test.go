@@ -1,7 +1,11 @@
 package transport
 
 import (
+	"fmt"
+	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
 )
@@ -20,10 +24,17 @@ func mockTopologyRoundRobin() *topology {
 	}
 
 	return &topology{
-		nodes: dummyNodes,
+		nodes: markUp(dummyNodes),
 	}
 }
 
+func markUp(nodes []*Node) []*Node {
+	for _, n := range nodes {
+		n.setStatus(statusUP)
+	}
+	return nodes
+}
+
 func mockCluster(t *topology, ks, localDC string) *Cluster {
 	c := Cluster{}
 	t.localDC = localDC
@@ -173,7 +184,7 @@ func mockTopologyTokenAwareSimpleStrategy() *topology {
 	}
 
 	return &topology{
-		nodes: dummyNodes,
+		nodes: markUp(dummyNodes),
 		policyInfo: policyInfo{
 			ring: ring,
 		},
@@ -288,7 +299,7 @@ func mockTopologyTokenAwareDCAwareStrategy() *topology {
 
 	return &topology{
 		dcRacks:    dcs,
-		nodes:      dummyNodes,
+		nodes:      markUp(dummyNodes),
 		policyInfo: policyInfo{ring: ring},
 		keyspaces:  ks,
 	}
@@ -335,3 +346,289 @@ func TestTokenAwareNetworkStrategyPolicy(t *testing.T) { //nolint:paralleltest /
 		})
 	}
 }
+
+func TestTokenAwarePolicySkipsUnavailableNodes(t *testing.T) { //nolint:paralleltest // Can't run in parallel.
+	top := mockTopologyRoundRobin()
+	c := mockCluster(top, "", "eu")
+	top.nodes[1].setStatus(statusDown)
+	top.nodes[3].pool = &ConnPool{breaker: newCircuitBreaker("4", CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}, nil)}
+	top.nodes[3].ReportResult(fmt.Errorf("read header: %w", io.EOF))
+
+	policy := NewTokenAwarePolicy("eu")
+	qi := c.NewQueryInfo()
+	// Node "2" is down, node "4" has open circuit breaker.
+	expected := []string{"1", "3", "5", "4"}
+	for offset, addr := range expected {
+		if res := policy.Node(qi, offset).addr; res != addr {
+			t.Fatalf("TestTokenAwarePolicySkipsUnavailableNodes: got \"%s\" but expected \"%s\"", res, addr)
+		}
+	}
+	if policy.Node(qi, len(expected)) != nil {
+		t.Fatalf("TestTokenAwarePolicySkipsUnavailableNodes: plan iter didn't return nil after making the whole cycle")
+	}
+}
+
+func TestTokenAwarePolicyOpenBreakerLast(t *testing.T) {
+	t.Parallel()
+
+	top := mockTopologyTokenAwareDCAwareStrategy()
+	c := mockCluster(top, "waw/her", "waw")
+	// Breakers of local replica "1" and remote replica "5" are open.
+	for _, i := range []int{0, 4} {
+		n := top.nodes[i]
+		n.pool = &ConnPool{breaker: newCircuitBreaker(n.addr, CircuitBreakerConfig{FailureThreshold: 1, OpenTimeout: time.Hour}, nil)}
+		n.ReportResult(fmt.Errorf("read header: %w", io.EOF))
+	}
+
+	policy := NewTokenAwarePolicy("waw")
+	qi, err := c.NewTokenAwareQueryInfo(0, "waw/her")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"4", "6", "8", "1", "5"}
+	for offset, addr := range expected {
+		if res := policy.Node(qi, offset); res == nil || res.addr != addr {
+			t.Fatalf("TestTokenAwarePolicyOpenBreakerLast: offset %d: got %v but expected \"%s\"", offset, res, addr)
+		}
+	}
+	if policy.Node(qi, len(expected)) != nil {
+		t.Fatalf("TestTokenAwarePolicyOpenBreakerLast: plan iter didn't return nil after making the whole cycle")
+	}
+}
+
+func TestRackAwarePolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		keyspace   string
+		localRack  string
+		tokenAware bool
+		offset     uint64
+		expected   []string
+	}{
+		{
+			name:       "token aware local rack r2",
+			keyspace:   "waw/her",
+			localRack:  "r2",
+			tokenAware: true,
+			expected:   []string{"4", "1", "5", "6", "8"},
+		},
+		{
+			name:       "token aware local rack r1",
+			keyspace:   "waw/her",
+			localRack:  "r1",
+			tokenAware: true,
+			expected:   []string{"1", "4", "5", "6", "8"},
+		},
+		{
+			name:      "round robin",
+			localRack: "r2",
+			expected:  []string{"3", "4", "1", "2", "5", "6", "7", "8"},
+		},
+		{
+			name:      "round robin with offset",
+			localRack: "r2",
+			offset:    1,
+			expected:  []string{"4", "3", "2", "1", "6", "7", "8", "5"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := mockCluster(mockTopologyTokenAwareDCAwareStrategy(), tc.keyspace, "waw")
+			qi := QueryInfo{
+				tokenAware: tc.tokenAware,
+				topology:   c.Topology(),
+				offset:     tc.offset,
+			}
+			policy := NewRackAwarePolicy("waw", tc.localRack)
+			for offset, addr := range tc.expected {
+				if res := policy.Node(qi, offset).addr; res != addr {
+					t.Fatalf("TestRackAwarePolicy: in test case %#+v: got \"%s\" but expected \"%s\"", tc, res, addr)
+				}
+			}
+			if policy.Node(qi, len(tc.expected)) != nil {
+				t.Fatalf("TestRackAwarePolicy: plan iter didn't return nil after making the whole cycle")
+			}
+		})
+	}
+}
+
+func TestComposablePolicies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name       string
+		keyspace   string
+		policy     HostSelectionPolicy
+		tokenAware bool
+		offset     uint64
+		expected   []string
+	}{
+		{
+			name:     "round robin",
+			policy:   NewRoundRobinPolicy(),
+			offset:   2,
+			expected: []string{"3", "4", "5", "6", "7", "8", "1", "2"},
+		},
+		{
+			name:     "dc aware round robin",
+			policy:   NewDCAwareRoundRobinPolicy("waw"),
+			offset:   1,
+			expected: []string{"2", "3", "4", "1", "6", "7", "8", "5"},
+		},
+		{
+			name:     "dc aware round robin with max remote nodes",
+			policy:   NewDCAwareRoundRobinPolicy("waw", MaxRemoteNodes(2)),
+			expected: []string{"1", "2", "3", "4", "5", "6"},
+		},
+		{
+			name:     "dc aware round robin without remote nodes",
+			policy:   NewDCAwareRoundRobinPolicy("waw", MaxRemoteNodes(0)),
+			expected: []string{"1", "2", "3", "4"},
+		},
+		{
+			name:       "token aware with max remote nodes",
+			keyspace:   "waw/her",
+			policy:     NewTokenAwarePolicy("waw", MaxRemoteNodes(1)),
+			tokenAware: true,
+			expected:   []string{"1", "4", "5"},
+		},
+		{
+			name:       "token aware ignores round robin for token",
+			keyspace:   "waw/her",
+			policy:     NewRoundRobinPolicy(),
+			tokenAware: true,
+			expected:   []string{"1", "2", "3", "4", "5", "6", "7", "8"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := mockCluster(mockTopologyTokenAwareDCAwareStrategy(), tc.keyspace, "waw")
+			qi := QueryInfo{
+				tokenAware: tc.tokenAware,
+				topology:   c.Topology(),
+				offset:     tc.offset,
+			}
+			for offset, addr := range tc.expected {
+				if res := tc.policy.Node(qi, offset); res == nil || res.addr != addr {
+					t.Fatalf("TestComposablePolicies: in test case %s: offset %d: got %v but expected \"%s\"", tc.name, offset, res, addr)
+				}
+			}
+			if tc.policy.Node(qi, len(tc.expected)) != nil {
+				t.Fatalf("TestComposablePolicies: plan iter didn't return nil after making the whole cycle")
+			}
+		})
+	}
+}
+
+func TestShuffleReplicas(t *testing.T) {
+	t.Parallel()
+
+	c := mockCluster(mockTopologyTokenAwareDCAwareStrategy(), "waw/her", "waw")
+	policy := NewTokenAwarePolicy("waw", ShuffleReplicas())
+
+	plan := func(qi QueryInfo) []string {
+		var res []string
+		for i := 0; ; i++ {
+			n := policy.Node(qi, i)
+			if n == nil {
+				return res
+			}
+			res = append(res, n.addr)
+		}
+	}
+
+	firsts := make(map[string]bool)
+	for offset := uint64(0); offset < 100; offset++ {
+		qi := QueryInfo{tokenAware: true, topology: c.Topology(), offset: offset}
+		p := plan(qi)
+		if len(p) != 5 {
+			t.Fatalf("got plan %v, expected 5 replicas", p)
+		}
+		if !(p[0] == "1" || p[0] == "4") || !(p[1] == "1" || p[1] == "4") {
+			t.Fatalf("got plan %v, expected local replicas first", p)
+		}
+		if cmp := plan(qi); strings.Join(cmp, ",") != strings.Join(p, ",") {
+			t.Fatalf("plan is not stable for the same query, got %v and %v", p, cmp)
+		}
+		firsts[p[2]] = true
+	}
+	if len(firsts) != 3 {
+		t.Fatalf("expected every remote replica to be tried first, got %v", firsts)
+	}
+}
+
+func TestTokenAwareEverywhereAndLocalStrategyPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		strategy strategy
+		localDC  string
+		token    Token
+		expected []string
+	}{
+		{
+			name:     "everywhere strategy",
+			strategy: strategy{class: everywhereStrategy},
+			localDC:  "waw",
+			token:    0,
+			expected: []string{"1", "2", "4", "3", "5", "6", "8", "7"},
+		},
+		{
+			name:     "everywhere strategy starts from token owner",
+			strategy: strategy{class: everywhereStrategy},
+			localDC:  "waw",
+			token:    260,
+			expected: []string{"4", "3", "1", "2", "8", "7", "5", "6"},
+		},
+		{
+			name:     "everywhere strategy without local dc",
+			strategy: strategy{class: everywhereStrategy},
+			token:    0,
+			expected: []string{"1", "5", "2", "6", "4", "8", "7", "3"},
+		},
+		{
+			name:     "local strategy",
+			strategy: strategy{class: localStrategy, rf: 1},
+			localDC:  "waw",
+			token:    260,
+			expected: []string{"1", "2", "3", "4", "5", "6", "7", "8"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			top := mockTopologyTokenAwareDCAwareStrategy()
+			top.keyspaces["ks"] = keyspace{strategy: tc.strategy}
+			c := mockCluster(top, "ks", tc.localDC)
+			qi, err := c.NewTokenAwareQueryInfo(tc.token, "ks")
+			if err != nil {
+				t.Fatal(err)
+			}
+			qi.offset = 0
+
+			policy := NewTokenAwarePolicy(tc.localDC)
+			for offset, addr := range tc.expected {
+				if res := policy.Node(qi, offset); res == nil || res.addr != addr {
+					t.Fatalf("TestTokenAwareEverywhereAndLocalStrategyPolicy: in test case %s: offset %d: got %v but expected \"%s\"", tc.name, offset, res, addr)
+				}
+			}
+			if policy.Node(qi, len(tc.expected)) != nil {
+				t.Fatalf("TestTokenAwareEverywhereAndLocalStrategyPolicy: plan iter didn't return nil after making the whole cycle")
+			}
+		})
+	}
+}
@@ -2,9 +2,9 @@ package transport
 
 import (
 	"fmt"
-	"log"
 	"math"
 	"net"
+	"sync"
 	"time"
 
 	. "github.com/mmatczuk/scylla-go-driver/frame/response"
@@ -12,15 +12,17 @@ import (
 	"go.uber.org/atomic"
 )
 
-const poolCloseShard = -1
-
 type ConnPool struct {
 	host         string
 	nrShards     int
 	msbIgnore    uint8
 	conns        []atomic.Value
-	connClosedCh chan int // notification channel for when connection is closed
+	connClosedCh chan *Conn // notification channel for when connection is closed, nil closes the pool
+	rotateCh     chan int   // shards which connections should be replaced
 	connObs      ConnObserver
+	logger       Logger
+	breaker      *circuitBreaker
+	done         chan struct{} // closed when pool refiller exits
 }
 
 func NewConnPool(host string, cfg ConnConfig) (*ConnPool, error) {
@@ -55,8 +57,13 @@ func isHeavyLoaded(conn *Conn) bool {
 	return conn.Waiting() > maxStreamID>>1
 }
 
+// isSaturated returns true if all stream IDs of conn may be in use.
+func isSaturated(conn *Conn) bool {
+	return conn.Waiting() >= maxStreamID
+}
+
 func (p *ConnPool) maybeReplaceWithLessBusyConn(conn *Conn) *Conn {
-	if lb := p.LeastBusyConn(); conn.Waiting()-lb.Waiting() > maxStreamID<<1/10 {
+	if lb := p.LeastBusyConn(); conn.Waiting()-lb.Waiting() > maxStreamID<<1/10 || isSaturated(conn) && !isSaturated(lb) {
 		if p.connObs != nil {
 			p.connObs.OnPickReplacedWithLessBusyConn(conn.Event())
 		}
@@ -82,6 +89,17 @@ func (p *ConnPool) LeastBusyConn() *Conn {
 	return leastBusyConn
 }
 
+// Waiting returns number of requests queued or in flight on all connections in the pool.
+func (p *ConnPool) Waiting() int {
+	n := 0
+	for i := range p.conns {
+		if conn := p.loadConn(i); conn != nil {
+			n += conn.Waiting()
+		}
+	}
+	return n
+}
+
 func (p *ConnPool) shardOf(token Token) int {
 	shards := uint64(p.nrShards)
 	z := uint64(token+math.MinInt64) << p.msbIgnore
@@ -102,22 +120,33 @@ func (p *ConnPool) loadConn(shard int) *Conn {
 	return conn
 }
 
-func (p *ConnPool) clearConn(shard int) bool {
-	conn, _ := p.conns[shard].Swap((*Conn)(nil)).(*Conn)
-	return conn != nil
+// clearConn removes conn from the pool, it returns false if conn was already removed or replaced.
+func (p *ConnPool) clearConn(conn *Conn) bool {
+	return p.conns[conn.Shard()].CompareAndSwap(conn, (*Conn)(nil))
 }
 
+// Close requests closing all connections, it does not wait for the pool to be closed.
 func (p *ConnPool) Close() {
-	p.connClosedCh <- poolCloseShard
+	p.connClosedCh <- nil
+}
+
+// Wait waits until pool is closed and all its connections go routines have exited.
+func (p *ConnPool) Wait() {
+	<-p.done
 }
 
 // closeAll is called by PoolRefiller.
 func (p *ConnPool) closeAll() {
+	var conns []*Conn
 	for i := range p.conns {
-		if conn, ok := p.conns[i].Swap((*Conn)(nil)).(*Conn); ok {
+		if conn, ok := p.conns[i].Swap((*Conn)(nil)).(*Conn); ok && conn != nil {
 			conn.Close()
+			conns = append(conns, conn)
 		}
 	}
+	for _, conn := range conns {
+		conn.wait()
+	}
 }
 
 type PoolRefiller struct {
@@ -125,6 +154,9 @@ type PoolRefiller struct {
 	pool   ConnPool
 	cfg    ConnConfig
 	active int
+
+	closing  chan struct{}  // closed when pool is being closed
+	retiring sync.WaitGroup // rotated connections waiting to be closed
 }
 
 func (r *PoolRefiller) init(host string) error {
@@ -133,7 +165,7 @@ func (r *PoolRefiller) init(host string) error {
 	}
 
 	span := startSpan()
-	conn, err := OpenConn(host, nil, r.cfg)
+	conn, err := OpenConn(r.cfg.nodeAddr(host), nil, r.cfg)
 	span.stop()
 	if err != nil {
 		if conn != nil {
@@ -149,15 +181,18 @@ func (r *PoolRefiller) init(host string) error {
 	}
 
 	ss := s.ScyllaSupported()
-	if r.cfg.TLSConfig != nil {
+	if !r.cfg.shardAware() {
+		// Node assigns connections opened to the regular port to the least busy shard.
+		r.addr = r.cfg.nodeAddr(host)
+	} else if r.cfg.TLSConfig != nil {
 		if v, ok := s.Options[ScyllaShardAwarePortSSL]; ok {
-			r.addr = net.JoinHostPort(host, v[0])
+			r.addr = r.cfg.translate(net.JoinHostPort(host, v[0]))
 		} else {
 			return fmt.Errorf("missing encrypted shard aware port information %v", s.Options)
 		}
 	} else {
 		if v, ok := s.Options[ScyllaShardAwarePort]; ok {
-			r.addr = net.JoinHostPort(host, v[0])
+			r.addr = r.cfg.translate(net.JoinHostPort(host, v[0]))
 		} else {
 			return fmt.Errorf("missing shard aware port information %v", s.Options)
 		}
@@ -168,9 +203,14 @@ func (r *PoolRefiller) init(host string) error {
 		nrShards:     int(ss.NrShards),
 		msbIgnore:    ss.MsbIgnore,
 		conns:        make([]atomic.Value, int(ss.NrShards)),
-		connClosedCh: make(chan int, int(ss.NrShards)+1),
+		connClosedCh: make(chan *Conn, int(ss.NrShards)+1),
+		rotateCh:     make(chan int, int(ss.NrShards)),
 		connObs:      r.cfg.ConnObserver,
+		logger:       r.cfg.logger(),
+		breaker:      newCircuitBreaker(host, r.cfg.CircuitBreaker, r.cfg.ConnObserver),
+		done:         make(chan struct{}),
 	}
+	r.closing = make(chan struct{})
 
 	conn.setOnClose(r.onConnClose)
 	r.pool.storeConn(conn)
@@ -183,10 +223,17 @@ func (r *PoolRefiller) init(host string) error {
 }
 
 func (r *PoolRefiller) onConnClose(conn *Conn) {
+	if conn.retired.Load() {
+		return
+	}
+	// Closes initiated by the driver, i.e. pool close or missed heartbeat, are not node failures.
+	if conn.failed.Load() {
+		r.pool.breaker.failure()
+	}
 	select {
-	case r.pool.connClosedCh <- conn.Shard():
+	case r.pool.connClosedCh <- conn:
 	default:
-		log.Printf("conn pool: ignoring conn %s close", conn)
+		r.pool.logger.Warn("ignoring connection close", "pool", &r.pool, "conn", conn)
 	}
 }
 
@@ -196,19 +243,25 @@ func (r *PoolRefiller) loop() {
 	r.fill()
 
 	timer := time.NewTicker(fillBackoff)
+	defer timer.Stop()
 	for {
 		select {
 		case <-timer.C:
 			r.fill()
-		case shard := <-r.pool.connClosedCh:
-			if shard == poolCloseShard {
+		case conn := <-r.pool.connClosedCh:
+			if conn == nil {
+				close(r.closing)
 				r.pool.closeAll()
+				r.retiring.Wait()
+				close(r.pool.done)
 				return
 			}
-			if r.pool.clearConn(shard) {
+			if r.pool.clearConn(conn) {
 				r.active--
 			}
 			r.fill()
+		case shard := <-r.pool.rotateCh:
+			r.rotate(shard)
 		}
 	}
 }
@@ -217,6 +270,10 @@ func (r *PoolRefiller) fill() {
 	if !r.needsFilling() {
 		return
 	}
+	if !r.cfg.shardAware() {
+		r.fillShardUnaware()
+		return
+	}
 
 	si := ShardInfo{
 		NrShards:  uint16(r.pool.nrShards),
@@ -233,6 +290,7 @@ func (r *PoolRefiller) fill() {
 		conn, err := OpenShardConn(r.addr, si, r.cfg)
 		span.stop()
 		if err != nil {
+			r.pool.breaker.failure()
 			if r.pool.connObs != nil {
 				r.pool.connObs.OnConnect(ConnectEvent{ConnEvent: ConnEvent{Addr: r.addr, Shard: si.Shard}, span: span, Err: err})
 			}
@@ -241,12 +299,15 @@ func (r *PoolRefiller) fill() {
 			}
 			continue
 		}
+		r.pool.breaker.success()
 		if r.pool.connObs != nil {
 			r.pool.connObs.OnConnect(ConnectEvent{ConnEvent: conn.Event(), span: span})
 		}
 
 		if conn.Shard() != i {
-			log.Fatalf("opened conn to wrong shard: expected %d got %d", i, conn.Shard())
+			r.pool.logger.Error("opened connection to wrong shard", "pool", &r.pool, "conn", conn, "expected_shard", i)
+			conn.Close()
+			continue
 		}
 		conn.setOnClose(r.onConnClose)
 		r.pool.storeConn(conn)
@@ -258,6 +319,38 @@ func (r *PoolRefiller) fill() {
 	}
 }
 
+// fillShardUnaware fills the pool when shard of a connection can't be chosen with local port,
+// i.e. when connecting through a proxy. Connections are kept on shards they land on.
+func (r *PoolRefiller) fillShardUnaware() {
+	for i := 0; i < 2*r.pool.nrShards && r.needsFilling(); i++ {
+		span := startSpan()
+		conn, err := OpenConn(r.addr, nil, r.cfg)
+		span.stop()
+		if err != nil {
+			r.pool.breaker.failure()
+			if r.pool.connObs != nil {
+				r.pool.connObs.OnConnect(ConnectEvent{ConnEvent: ConnEvent{Addr: r.addr}, span: span, Err: err})
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			continue
+		}
+		r.pool.breaker.success()
+
+		if r.pool.loadConn(conn.Shard()) != nil {
+			conn.Close()
+			continue
+		}
+		if r.pool.connObs != nil {
+			r.pool.connObs.OnConnect(ConnectEvent{ConnEvent: conn.Event(), span: span})
+		}
+		conn.setOnClose(r.onConnClose)
+		r.pool.storeConn(conn)
+		r.active++
+	}
+}
+
 func (r *PoolRefiller) needsFilling() bool {
 	return r.active < r.pool.nrShards
 }
@@ -0,0 +1,66 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	"github.com/mmatczuk/scylla-go-driver/internal/testutil"
+	"go.uber.org/atomic"
+)
+
+// fakeShardServer answers OPTIONS with sharding information of a single shard node and accepts startup.
+func fakeShardServer(h frame.Header, body []byte) (frame.OpCode, []byte) {
+	switch h.OpCode {
+	case frame.OpOptions:
+		return frame.OpSupported, testutil.Supported()
+	case frame.OpStartup:
+		return frame.OpReady, nil
+	default:
+		return 0, nil
+	}
+}
+
+func TestConnPoolRotateAndClose(t *testing.T) {
+	t.Parallel()
+
+	dials := atomic.NewInt32(0)
+	cfg := DefaultConnConfig("")
+	cfg.Dialer = DialerFunc(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials.Inc()
+		return newFakeConn(t, fakeShardServer), nil
+	})
+
+	p, err := NewConnPool("10.0.0.1", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := p.loadConn(0)
+
+	p.Rotate(0)
+	deadline := time.Now().Add(time.Second)
+	for p.loadConn(0) == old || p.loadConn(0) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("connection not rotated")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := dials.Load(); v != 2 {
+		t.Fatalf("got %d dials, expected 2", v)
+	}
+	if v := p.breaker.State(); v != BreakerClosed {
+		t.Fatalf("got breaker state %v, rotation must not be reported as failure", v)
+	}
+	// Late close notification of the rotated connection must not remove its replacement.
+	if cur := p.loadConn(0); p.clearConn(old) || p.loadConn(0) != cur {
+		t.Fatal("replacement connection removed")
+	}
+
+	p.Close()
+	p.Wait()
+	if p.loadConn(0) != nil {
+		t.Fatal("connection not closed")
+	}
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ReplicaInfo describes a node owning a token and the shard owning the token on that node.
+type ReplicaInfo struct {
+	Host HostInfo
+	// Shard is -1 if the node is not connected yet.
+	Shard int
+}
+
+// Shard returns the shard owning token on n or -1 if the node is not connected yet.
+func (n *Node) Shard(token Token) int {
+	if n.pool == nil {
+		return -1
+	}
+	return n.pool.shardOf(token)
+}
+
+// Replicas returns replicas of token in keyspace ks, local datacenter replicas go first.
+// If ks is empty the default keyspace is used. Replicas of keyspaces other than
+// the default one are computed on the first call and cached until topology changes.
+func (c *Cluster) Replicas(ks string, token Token) ([]ReplicaInfo, error) {
+	top := c.Topology()
+	if ks == "" {
+		ks = c.cfg.Keyspace
+	}
+	k, ok := top.keyspaces[ks]
+	if !ok {
+		return nil, top.unknownKeyspaceError(ks)
+	}
+	if !k.strategy.known() {
+		return nil, fmt.Errorf("keyspace %q: unsupported replication strategy %s", ks, k.strategy.class)
+	}
+
+	pi := top.policyInfo
+	if ks != c.cfg.Keyspace {
+		pi = c.replicas.get(top, ks, k)
+	}
+	if len(pi.ring) == 0 {
+		return nil, nil
+	}
+
+	e := pi.ring[pi.ring.tokenLowerBound(token)]
+	res := make([]ReplicaInfo, 0, len(e.localReplicas)+len(e.remoteReplicas))
+	for _, v := range [][]*Node{e.localReplicas, e.remoteReplicas} {
+		for _, n := range v {
+			res = append(res, ReplicaInfo{
+				Host:  n.Info(),
+				Shard: n.Shard(token),
+			})
+		}
+	}
+	return res, nil
+}
+
+// CheckKeyspace returns error if keyspace ks is not known, if ks is empty the default keyspace is checked.
+func (c *Cluster) CheckKeyspace(ks string) error {
+	top := c.Topology()
+	if ks == "" {
+		ks = c.cfg.Keyspace
+	}
+	if _, ok := top.keyspaces[ks]; !ok {
+		return top.unknownKeyspaceError(ks)
+	}
+	return nil
+}
+
+// replicaCache holds replicas of keyspaces other than the default one computed for topology.
+type replicaCache struct {
+	topology *topology
+	infos    map[string]policyInfo
+	mu       sync.Mutex // mu guards topology and infos
+}
+
+// get returns replicas of keyspace name in t, cache is reset when t differs from the cached topology.
+func (c *replicaCache) get(t *topology, name string, ks keyspace) policyInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.topology != t {
+		c.topology = t
+		c.infos = make(map[string]policyInfo)
+	}
+	if pi, ok := c.infos[name]; ok {
+		return pi
+	}
+	pi, _ := t.policyInfoFor(ks)
+	c.infos[name] = pi
+	return pi
+}
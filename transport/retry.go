@@ -1,6 +1,9 @@
 package transport
 
 import (
+	"math/rand"
+	"time"
+
 	"github.com/mmatczuk/scylla-go-driver/frame"
 	. "github.com/mmatczuk/scylla-go-driver/frame/response"
 )
@@ -19,6 +22,25 @@ const (
 	DontRetry
 )
 
+// Retry tells if and how the failed query should be retried.
+type Retry struct {
+	Decision RetryDecision
+	// Consistency replaces consistency of the retried query if ChangeConsistency is set.
+	Consistency       frame.Consistency
+	ChangeConsistency bool
+	// Delay is the time to wait before the query is retried.
+	Delay time.Duration
+}
+
+// RetryWithConsistency returns Retry which retries the query on the same node with consistency c.
+func RetryWithConsistency(c frame.Consistency) Retry {
+	return Retry{
+		Decision:          RetrySameNode,
+		Consistency:       c,
+		ChangeConsistency: true,
+	}
+}
+
 type RetryPolicy interface {
 	NewRetryDecider() RetryDecider
 }
@@ -26,7 +48,7 @@ type RetryPolicy interface {
 // RetryDecider should be used for just one query that we want to retry.
 // After that it should be discarded or reset.
 type RetryDecider interface {
-	Decide(RetryInfo) RetryDecision
+	Decide(RetryInfo) Retry
 	Reset()
 }
 
@@ -42,8 +64,8 @@ func NewFallthroughRetryPolicy() RetryPolicy {
 
 type FallthroughRetryDecider struct{}
 
-func (FallthroughRetryDecider) Decide(_ RetryInfo) RetryDecision {
-	return DontRetry
+func (FallthroughRetryDecider) Decide(_ RetryInfo) Retry {
+	return Retry{Decision: DontRetry}
 }
 
 func (FallthroughRetryDecider) Reset() {}
@@ -69,13 +91,13 @@ type DefaultRetryDecider struct {
 	wasWriteTimeout bool
 }
 
-func (d *DefaultRetryDecider) Decide(ri RetryInfo) RetryDecision {
+func (d *DefaultRetryDecider) Decide(ri RetryInfo) Retry {
 	v, ok := ri.Error.(CodedError)
 	if !ok {
 		if ri.Idempotent {
-			return RetryNextNode
+			return Retry{Decision: RetryNextNode}
 		} else {
-			return DontRetry
+			return Retry{Decision: DontRetry}
 		}
 	}
 	switch v.ErrorCode() {
@@ -83,9 +105,9 @@ func (d *DefaultRetryDecider) Decide(ri RetryInfo) RetryDecision {
 	// Retry on a different one if possible.
 	case frame.ErrCodeOverloaded, frame.ErrCodeServer, frame.ErrCodeTruncate:
 		if ri.Idempotent {
-			return RetryNextNode
+			return Retry{Decision: RetryNextNode}
 		} else {
-			return DontRetry
+			return Retry{Decision: DontRetry}
 		}
 	// Unavailable - the current node believes that not enough nodes
 	// are alive to satisfy specified consistency requirements.
@@ -95,13 +117,13 @@ func (d *DefaultRetryDecider) Decide(ri RetryInfo) RetryDecision {
 	case frame.ErrCodeUnavailable:
 		if !d.wasUnavailable {
 			d.wasUnavailable = true
-			return RetryNextNode
+			return Retry{Decision: RetryNextNode}
 		} else {
-			return DontRetry
+			return Retry{Decision: DontRetry}
 		}
 	// Is Bootstrapping - node can't execute the query, we should try another one.
 	case frame.ErrCodeBootstrapping:
-		return RetryNextNode
+		return Retry{Decision: RetryNextNode}
 	// Read Timeout - coordinator didn't receive enough replies in time.
 	// Retry at most once and only if there were actually enough replies
 	// to satisfy consistency, but they were all just checksums (DataPresent == true).
@@ -112,9 +134,9 @@ func (d *DefaultRetryDecider) Decide(ri RetryInfo) RetryDecision {
 		err := v.(ReadTimeoutError)
 		if !d.wasReadTimeout && err.Received >= err.BlockFor && err.DataPresent {
 			d.wasReadTimeout = true
-			return RetrySameNode
+			return Retry{Decision: RetrySameNode}
 		} else {
-			return DontRetry
+			return Retry{Decision: DontRetry}
 		}
 	// Write timeout - coordinator didn't receive enough replies in time.
 	// Retry at most once and only for BatchLog write.
@@ -124,12 +146,12 @@ func (d *DefaultRetryDecider) Decide(ri RetryInfo) RetryDecision {
 		err := v.(WriteTimeoutError)
 		if !d.wasWriteTimeout && ri.Idempotent && err.WriteType == frame.BatchLog {
 			d.wasWriteTimeout = true
-			return RetrySameNode
+			return Retry{Decision: RetrySameNode}
 		} else {
-			return DontRetry
+			return Retry{Decision: DontRetry}
 		}
 	default:
-		return DontRetry
+		return Retry{Decision: DontRetry}
 	}
 }
 
@@ -138,3 +160,180 @@ func (d *DefaultRetryDecider) Reset() {
 	d.wasReadTimeout = false
 	d.wasWriteTimeout = false
 }
+
+// DowngradingConsistencyRetryPolicy retries at most once, it may lower consistency of the retried
+// query to the level that is likely to succeed given the number of replicas that responded or are alive.
+// Please note that the retried query may succeed with weaker consistency guarantees than requested.
+type DowngradingConsistencyRetryPolicy struct{}
+
+func (*DowngradingConsistencyRetryPolicy) NewRetryDecider() RetryDecider {
+	return &DowngradingConsistencyRetryDecider{}
+}
+
+func NewDowngradingConsistencyRetryPolicy() RetryPolicy {
+	return &DowngradingConsistencyRetryPolicy{}
+}
+
+type DowngradingConsistencyRetryDecider struct {
+	retried bool
+}
+
+func (d *DowngradingConsistencyRetryDecider) Decide(ri RetryInfo) Retry {
+	if d.retried {
+		return Retry{Decision: DontRetry}
+	}
+	dec := d.decide(ri)
+	if dec.Decision != DontRetry {
+		d.retried = true
+	}
+	return dec
+}
+
+func (d *DowngradingConsistencyRetryDecider) decide(ri RetryInfo) Retry {
+	v, ok := ri.Error.(CodedError)
+	if !ok {
+		if ri.Idempotent {
+			return Retry{Decision: RetryNextNode}
+		}
+		return Retry{Decision: DontRetry}
+	}
+	switch v.ErrorCode() {
+	case frame.ErrCodeOverloaded, frame.ErrCodeServer, frame.ErrCodeTruncate:
+		if ri.Idempotent {
+			return Retry{Decision: RetryNextNode}
+		}
+		return Retry{Decision: DontRetry}
+	case frame.ErrCodeBootstrapping:
+		return Retry{Decision: RetryNextNode}
+	// Unavailable - if serial consistency can't be achieved, try a different coordinator,
+	// otherwise retry with the consistency that alive replicas are able to satisfy.
+	case frame.ErrCodeUnavailable:
+		err := v.(UnavailableError)
+		if isSerialConsistency(err.Consistency) {
+			return Retry{Decision: RetryNextNode}
+		}
+		return maxLikelyToWork(err.Alive)
+	// Read Timeout - if not enough replicas responded, retry with the consistency
+	// they are able to satisfy. If enough replicas responded but data was not retrieved,
+	// retry with the same consistency as in DefaultRetryPolicy.
+	case frame.ErrCodeReadTimeout:
+		err := v.(ReadTimeoutError)
+		switch {
+		case isSerialConsistency(err.Consistency):
+			return Retry{Decision: DontRetry}
+		case err.Received < err.BlockFor:
+			return maxLikelyToWork(err.Received)
+		case !err.DataPresent:
+			return Retry{Decision: RetrySameNode}
+		default:
+			return Retry{Decision: DontRetry}
+		}
+	// Write Timeout - retry only idempotent queries. Unlogged batch is retried with the consistency
+	// the responding replicas are able to satisfy, batch log write is retried with the same consistency.
+	case frame.ErrCodeWriteTimeout:
+		err := v.(WriteTimeoutError)
+		if !ri.Idempotent {
+			return Retry{Decision: DontRetry}
+		}
+		switch err.WriteType {
+		case frame.UnloggedBatch:
+			return maxLikelyToWork(err.Received)
+		case frame.BatchLog:
+			return Retry{Decision: RetrySameNode}
+		default:
+			return Retry{Decision: DontRetry}
+		}
+	default:
+		return Retry{Decision: DontRetry}
+	}
+}
+
+func (d *DowngradingConsistencyRetryDecider) Reset() {
+	d.retried = false
+}
+
+func isSerialConsistency(c frame.Consistency) bool {
+	return c == frame.SERIAL || c == frame.LOCALSERIAL
+}
+
+// maxLikelyToWork returns decision to retry with the highest consistency level that n replicas can satisfy.
+func maxLikelyToWork(n frame.Int) Retry {
+	switch {
+	case n >= 3:
+		return RetryWithConsistency(frame.THREE)
+	case n == 2:
+		return RetryWithConsistency(frame.TWO)
+	case n == 1:
+		return RetryWithConsistency(frame.ONE)
+	default:
+		return Retry{Decision: DontRetry}
+	}
+}
+
+// ExponentialBackoffRetryPolicy delays retries decided by the wrapped policy.
+// Delay of the n-th retry is picked at random from [d/2, d], where d = min(MinDelay * 2^n, MaxDelay).
+// After MaxRetries retries the query is not retried anymore.
+type ExponentialBackoffRetryPolicy struct {
+	Policy     RetryPolicy
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+	MaxRetries int
+}
+
+func (p *ExponentialBackoffRetryPolicy) NewRetryDecider() RetryDecider {
+	return &ExponentialBackoffRetryDecider{
+		policy:  p,
+		decider: p.Policy.NewRetryDecider(),
+	}
+}
+
+func NewExponentialBackoffRetryPolicy(p RetryPolicy, minDelay, maxDelay time.Duration, maxRetries int) RetryPolicy {
+	return &ExponentialBackoffRetryPolicy{
+		Policy:     p,
+		MinDelay:   minDelay,
+		MaxDelay:   maxDelay,
+		MaxRetries: maxRetries,
+	}
+}
+
+type ExponentialBackoffRetryDecider struct {
+	policy  *ExponentialBackoffRetryPolicy
+	decider RetryDecider
+	retries int
+}
+
+func (d *ExponentialBackoffRetryDecider) Decide(ri RetryInfo) Retry {
+	if d.retries >= d.policy.MaxRetries {
+		return Retry{Decision: DontRetry}
+	}
+	dec := d.decider.Decide(ri)
+	if dec.Decision == DontRetry {
+		return dec
+	}
+	delay := d.backoff()
+	d.retries++
+	if delay > dec.Delay {
+		dec.Delay = delay
+	}
+	return dec
+}
+
+func (d *ExponentialBackoffRetryDecider) backoff() time.Duration {
+	delay := d.policy.MinDelay
+	for i := 0; i < d.retries && delay < d.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > d.policy.MaxDelay {
+		delay = d.policy.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func (d *ExponentialBackoffRetryDecider) Reset() {
+	d.retries = 0
+	d.decider.Reset()
+}
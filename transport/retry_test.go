@@ -3,6 +3,7 @@ package transport
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
 	. "github.com/mmatczuk/scylla-go-driver/frame/response"
@@ -247,11 +248,11 @@ func TestDefaultRetryPolicy(t *testing.T) {
 			}
 
 			decider := NewDefaultRetryPolicy().NewRetryDecider()
-			res := decider.Decide(ri)
+			res := decider.Decide(ri).Decision
 			if res != tc.res1 {
 				t.Fatalf("First retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
 			}
-			res = decider.Decide(ri)
+			res = decider.Decide(ri).Decision
 			if res != tc.res2 {
 				t.Fatalf("Second retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
 			}
@@ -259,14 +260,237 @@ func TestDefaultRetryPolicy(t *testing.T) {
 			ri.Idempotent = true
 
 			decider = NewDefaultRetryPolicy().NewRetryDecider()
-			res = decider.Decide(ri)
+			res = decider.Decide(ri).Decision
 			if res != tc.resIdem1 {
 				t.Fatalf("(Idempotent) First retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
 			}
-			res = decider.Decide(ri)
+			res = decider.Decide(ri).Decision
 			if res != tc.resIdem2 {
 				t.Fatalf("(Idempotent) Second retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
 			}
 		})
 	}
 }
+
+func TestDowngradingConsistencyRetryPolicy(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		error   error
+		res     Retry
+		resIdem Retry
+	}{
+		{
+			name:    "Syntax",
+			error:   ScyllaError{Code: frame.ErrCodeSyntax},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: DontRetry},
+		},
+		{
+			name:    "Overloaded",
+			error:   ScyllaError{Code: frame.ErrCodeOverloaded},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: RetryNextNode},
+		},
+		{
+			name:    "IO",
+			error:   fmt.Errorf("dummy error"),
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: RetryNextNode},
+		},
+		{
+			name:    "IsBootstrapping",
+			error:   ScyllaError{Code: frame.ErrCodeBootstrapping},
+			res:     Retry{Decision: RetryNextNode},
+			resIdem: Retry{Decision: RetryNextNode},
+		},
+		{
+			name: "Unavailable 2 alive",
+			error: UnavailableError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeUnavailable},
+				Consistency: frame.QUORUM,
+				Required:    3,
+				Alive:       2,
+			},
+			res:     RetryWithConsistency(frame.TWO),
+			resIdem: RetryWithConsistency(frame.TWO),
+		},
+		{
+			name: "Unavailable 0 alive",
+			error: UnavailableError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeUnavailable},
+				Consistency: frame.ONE,
+				Required:    1,
+				Alive:       0,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: DontRetry},
+		},
+		{
+			name: "Unavailable serial",
+			error: UnavailableError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeUnavailable},
+				Consistency: frame.SERIAL,
+				Required:    3,
+				Alive:       1,
+			},
+			res:     Retry{Decision: RetryNextNode},
+			resIdem: Retry{Decision: RetryNextNode},
+		},
+		{
+			name: "ReadTimeout not enough responses",
+			error: ReadTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeReadTimeout},
+				Consistency: frame.ALL,
+				Received:    4,
+				BlockFor:    5,
+				DataPresent: true,
+			},
+			res:     RetryWithConsistency(frame.THREE),
+			resIdem: RetryWithConsistency(frame.THREE),
+		},
+		{
+			name: "ReadTimeout enough responses, data == false",
+			error: ReadTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeReadTimeout},
+				Consistency: frame.TWO,
+				Received:    2,
+				BlockFor:    2,
+				DataPresent: false,
+			},
+			res:     Retry{Decision: RetrySameNode},
+			resIdem: Retry{Decision: RetrySameNode},
+		},
+		{
+			name: "ReadTimeout enough responses, data == true",
+			error: ReadTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeReadTimeout},
+				Consistency: frame.TWO,
+				Received:    2,
+				BlockFor:    2,
+				DataPresent: true,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: DontRetry},
+		},
+		{
+			name: "ReadTimeout serial",
+			error: ReadTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeReadTimeout},
+				Consistency: frame.LOCALSERIAL,
+				Received:    0,
+				BlockFor:    2,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: DontRetry},
+		},
+		{
+			name: "WriteTimeout write type == UnloggedBatch",
+			error: WriteTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeWriteTimeout},
+				Consistency: frame.QUORUM,
+				Received:    1,
+				BlockFor:    2,
+				WriteType:   frame.UnloggedBatch,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: RetryWithConsistency(frame.ONE),
+		},
+		{
+			name: "WriteTimeout write type == BatchLog",
+			error: WriteTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeWriteTimeout},
+				Consistency: frame.TWO,
+				Received:    1,
+				BlockFor:    2,
+				WriteType:   frame.BatchLog,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: RetrySameNode},
+		},
+		{
+			name: "WriteTimeout write type == Simple",
+			error: WriteTimeoutError{
+				ScyllaError: ScyllaError{Code: frame.ErrCodeWriteTimeout},
+				Consistency: frame.TWO,
+				Received:    1,
+				BlockFor:    2,
+				WriteType:   frame.Simple,
+			},
+			res:     Retry{Decision: DontRetry},
+			resIdem: Retry{Decision: DontRetry},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ri := RetryInfo{
+				Error:      tc.error,
+				Idempotent: false,
+			}
+
+			decider := NewDowngradingConsistencyRetryPolicy().NewRetryDecider()
+			if res := decider.Decide(ri); res != tc.res {
+				t.Fatalf("First retry decision for: %+#v, wanted: %v, got: %v", ri, tc.res, res)
+			}
+			if res := decider.Decide(ri); res.Decision != DontRetry {
+				t.Fatalf("Second retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
+			}
+
+			ri.Idempotent = true
+
+			decider = NewDowngradingConsistencyRetryPolicy().NewRetryDecider()
+			if res := decider.Decide(ri); res != tc.resIdem {
+				t.Fatalf("(Idempotent) First retry decision for: %+#v, wanted: %v, got: %v", ri, tc.resIdem, res)
+			}
+			if res := decider.Decide(ri); res.Decision != DontRetry {
+				t.Fatalf("(Idempotent) Second retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
+			}
+
+			decider.Reset()
+			if res := decider.Decide(ri); res != tc.resIdem {
+				t.Fatalf("(Idempotent) Retry decision after reset for: %+#v, wanted: %v, got: %v", ri, tc.resIdem, res)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	const (
+		minDelay   = 10 * time.Millisecond
+		maxDelay   = 50 * time.Millisecond
+		maxRetries = 5
+	)
+	ri := RetryInfo{
+		Error:      ScyllaError{Code: frame.ErrCodeBootstrapping},
+		Idempotent: true,
+	}
+	expected := []time.Duration{10, 20, 40, 50, 50}
+
+	decider := NewExponentialBackoffRetryPolicy(NewDefaultRetryPolicy(), minDelay, maxDelay, maxRetries).NewRetryDecider()
+	for i := 0; i < 2; i++ {
+		for n, d := range expected {
+			d *= time.Millisecond
+			res := decider.Decide(ri)
+			if res.Decision != RetryNextNode {
+				t.Fatalf("Retry %d decision for: %+#v, wanted: %v, got: %v", n, ri, RetryNextNode, res)
+			}
+			if res.Delay < d/2 || res.Delay > d {
+				t.Fatalf("Retry %d delay %s out of range [%s, %s]", n, res.Delay, d/2, d)
+			}
+		}
+		if res := decider.Decide(ri); res.Decision != DontRetry {
+			t.Fatalf("Retry decision after %d retries for: %+#v, wanted: %v, got: %v", maxRetries, ri, DontRetry, res)
+		}
+		decider.Reset()
+	}
+
+	ri.Error = ScyllaError{Code: frame.ErrCodeSyntax}
+	if res := decider.Decide(ri); res.Decision != DontRetry {
+		t.Fatalf("Retry decision for: %+#v, wanted: %v, got: %v", ri, DontRetry, res)
+	}
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CredentialsProvider returns username and password, it's called for every new connection
+// authenticated with PasswordAuthenticator so that credentials can be rotated.
+type CredentialsProvider func() (username, password string, err error)
+
+// rotateDrainTimeout is the maximal time a rotated connection waits for in flight requests before it's closed.
+const rotateDrainTimeout = 10 * time.Second
+
+// Rotate asks pool refiller to replace connection to the shard with a new one, the old connection
+// is closed once its in flight requests are done. It does not wait for the replacement.
+func (p *ConnPool) Rotate(shard int) {
+	select {
+	case p.rotateCh <- shard:
+	default:
+		p.logger.Warn("ignoring rotation, rotation queue is full", "pool", p, "shard", shard)
+	}
+}
+
+func (r *PoolRefiller) rotate(shard int) {
+	old := r.pool.loadConn(shard)
+	if old == nil {
+		return
+	}
+
+	if r.cfg.shardAware() {
+		si := ShardInfo{
+			NrShards:  uint16(r.pool.nrShards),
+			MsbIgnore: r.pool.msbIgnore,
+			Shard:     uint16(shard),
+		}
+		conn, err := OpenShardConn(r.addr, si, r.cfg)
+		if err != nil {
+			r.pool.logger.Warn("failed to rotate connection", "pool", &r.pool, "shard", shard, "error", err)
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		old.retired.Store(true)
+		conn.setOnClose(r.onConnClose)
+		r.pool.storeConn(conn)
+	} else {
+		old.retired.Store(true)
+		if r.pool.clearConn(old) {
+			r.active--
+		}
+		r.fill()
+	}
+
+	r.retiring.Add(1)
+	go func() {
+		defer r.retiring.Done()
+		r.retire(old)
+	}()
+}
+
+// retire closes conn after its in flight requests are done, after rotateDrainTimeout or when pool is closed.
+func (r *PoolRefiller) retire(conn *Conn) {
+	defer func() {
+		conn.Close()
+		conn.wait()
+	}()
+
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := Now().Add(rotateDrainTimeout)
+	for conn.Waiting() > 0 && Now().Before(deadline) {
+		select {
+		case <-r.closing:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RotateConnections reopens control connection and gradually replaces all pool connections with
+// new ones waiting pause between connections, new connections pick up current credentials and certificates.
+// It blocks for about pause times number of pool connections, or until cluster is closed.
+func (c *Cluster) RotateConnections(pause time.Duration) {
+	c.RequestReopenControl()
+	for _, n := range c.Topology().nodes {
+		if n.pool == nil {
+			continue
+		}
+		for i := range n.pool.conns {
+			select {
+			case <-c.done:
+				return
+			case <-time.After(pause):
+			}
+			n.pool.Rotate(i)
+		}
+	}
+}
+
+// CertificateReloader loads client certificate from files and reloads it when files change,
+// use GetClientCertificate as tls.Config.GetClientCertificate.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	cert    *tls.Certificate
+	modTime time.Time
+	mu      sync.Mutex // mu guards cert and modTime
+
+	// Logger is used to report reloading errors, if not set StdLogger is used.
+	Logger Logger
+}
+
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetClientCertificate returns current certificate, if reloading of changed files fails
+// the previous certificate is returned.
+func (r *CertificateReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reload(); err != nil {
+		r.logger().Warn("failed to reload certificate, using previous certificate", "cert_file", r.certFile, "error", err)
+	}
+	return r.cert, nil
+}
+
+func (r *CertificateReloader) logger() Logger {
+	if r.Logger != nil {
+		return r.Logger
+	}
+	return StdLogger{}
+}
+
+// reload must be called with mu held or before r is shared.
+func (r *CertificateReloader) reload() error {
+	modTime, err := r.lastModified()
+	if err != nil {
+		return err
+	}
+	if r.cert != nil && !modTime.After(r.modTime) {
+		return nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	return nil
+}
+
+func (r *CertificateReloader) lastModified() (time.Time, error) {
+	var t time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		s, err := os.Stat(f)
+		if err != nil {
+			return t, fmt.Errorf("stat certificate: %w", err)
+		}
+		if s.ModTime().After(t) {
+			t = s.ModTime()
+		}
+	}
+	return t, nil
+}
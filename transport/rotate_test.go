@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConnCredentialsProvider(t *testing.T) {
+	t.Parallel()
+
+	calls := 0
+	cfg := DefaultConnConfig("")
+	cfg.Username = "ignored"
+	cfg.CredentialsProvider = func() (string, string, error) {
+		calls++
+		return fmt.Sprintf("user%d", calls), "pass", nil
+	}
+
+	for i := 1; i <= 2; i++ {
+		h, got := fakeAuthServer("org.apache.cassandra.auth.PasswordAuthenticator")
+		conn, err := WrapConn(newFakeConn(t, h), cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+
+		if expected := fmt.Sprintf("\x00user%d\x00pass", i); len(*got) != 1 || (*got)[0] != expected {
+			t.Fatalf("got responses %q, expected %q", *got, expected)
+		}
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	writeTestCertificate(t, certFile, keyFile, "first", time.Now().Add(-time.Minute))
+	r, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commonName := func() string {
+		t.Helper()
+		c, err := r.GetClientCertificate(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(c.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leaf.Subject.CommonName
+	}
+
+	if v := commonName(); v != "first" {
+		t.Fatalf("got certificate %s, expected first", v)
+	}
+
+	writeTestCertificate(t, certFile, keyFile, "second", time.Now())
+	if v := commonName(); v != "second" {
+		t.Fatalf("got certificate %s, expected second", v)
+	}
+
+	if err := os.WriteFile(certFile, []byte("broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if v := commonName(); v != "second" {
+		t.Fatalf("got certificate %s, expected previous certificate", v)
+	}
+}
+
+// writeTestCertificate writes self-signed certificate and its key setting files modification time to modTime.
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
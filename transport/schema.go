@@ -0,0 +1,216 @@
+package transport
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+)
+
+var (
+	keyspaceByNameQuery = Statement{
+		Content:     "SELECT keyspace_name, replication FROM system_schema.keyspaces WHERE keyspace_name = ?",
+		Consistency: frame.ONE,
+	}
+)
+
+// schemaChange identifies a keyspace that has to be reloaded.
+type schemaChange struct {
+	keyspace string
+}
+
+const (
+	// schemaRefreshDelay is the quiet period after the last schema change event before schema is reloaded.
+	schemaRefreshDelay = 200 * time.Millisecond
+	// schemaRefreshMaxDelay caps how long a burst of DDL can postpone the reload.
+	schemaRefreshMaxDelay = 2 * time.Second
+)
+
+// schemaChanges debounces schema change events, so that a burst of DDL results in a single reload.
+type schemaChanges struct {
+	pending map[schemaChange]struct{}
+	events  []ClusterEvent // events to be published after reload
+	first   time.Time
+	timer   *time.Timer
+	mu      sync.Mutex // mu guards pending, events, first and timer
+}
+
+// add records changes and schedules f to be called once no new changes arrive for schemaRefreshDelay.
+func (s *schemaChanges) add(changes []schemaChange, events []ClusterEvent, f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == nil {
+		s.pending = make(map[schemaChange]struct{})
+	}
+	for _, ch := range changes {
+		s.pending[ch] = struct{}{}
+	}
+	s.events = append(s.events, events...)
+
+	now := Now()
+	if s.timer == nil {
+		s.first = now
+		s.timer = time.AfterFunc(schemaRefreshDelay, f)
+		return
+	}
+	if now.Sub(s.first) < schemaRefreshMaxDelay {
+		s.timer.Reset(schemaRefreshDelay)
+	}
+}
+
+// take returns all pending changes with their events and resets the debounce timer.
+func (s *schemaChanges) take() ([]schemaChange, []ClusterEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	res := make([]schemaChange, 0, len(s.pending))
+	for ch := range s.pending {
+		res = append(res, ch)
+	}
+	events := s.events
+	s.pending = nil
+	s.events = nil
+	return res, events
+}
+
+func (s *schemaChanges) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.pending = nil
+	s.events = nil
+}
+
+func (c *Cluster) handleSchemaChange(v *SchemaChange) {
+	c.cfg.logger().Info("cluster: handle schema change", "change", v.Change, "target", v.Target, "keyspace", v.Keyspace, "object", v.Object)
+	ev := makeSchemaChangeEvent(v)
+	switch v.Target {
+	case frame.Keyspace:
+		ch := schemaChange{keyspace: v.Keyspace}
+		c.schemaChanges.add([]schemaChange{ch}, []ClusterEvent{ev}, c.RequestSchemaRefresh)
+	default:
+		// Only replication of keyspaces is part of the cluster metadata.
+		c.subscribers.publish(ev)
+	}
+}
+
+// tryRefreshSchema reloads schema elements affected by pending schema changes and publishes their events.
+// In case of error changes are requeued and control connection is reopened.
+func (c *Cluster) tryRefreshSchema() {
+	changes, events := c.schemaChanges.take()
+	if len(changes) == 0 {
+		return
+	}
+	if err := c.refreshSchema(changes); err != nil {
+		c.cfg.logger().Error("cluster: refresh schema failed", "error", err)
+		c.schemaChanges.add(changes, events, c.RequestSchemaRefresh)
+		c.RequestReopenControl()
+		return
+	}
+	c.subscribers.publish(events...)
+}
+
+// refreshSchema reloads only the keyspaces given in changes.
+// Old topology is replaced with the new one atomically to prevent dirty reads.
+func (c *Cluster) refreshSchema(changes []schemaChange) error {
+	c.cfg.logger().Debug("cluster: refresh schema")
+	old := c.Topology()
+	ks := make(ksMap, len(old.keyspaces))
+	for k, v := range old.keyspaces {
+		ks[k] = v
+	}
+
+	for _, ch := range changes {
+		if err := c.refreshKeyspace(ks, ch.keyspace); err != nil {
+			return err
+		}
+	}
+
+	c.setTopology(old.withKeyspaces(ks, c.cfg.Keyspace))
+	return nil
+}
+
+func (c *Cluster) refreshKeyspace(ks ksMap, name string) error {
+	res, err := c.control.Query(withTextValues(keyspaceByNameQuery, name), nil)
+	if err != nil {
+		return fmt.Errorf("query keyspace %s: %w", name, err)
+	}
+	if len(res.Rows) == 0 {
+		delete(ks, name)
+		return nil
+	}
+	stg, err := parseStrategyFromRow(res.Rows[0])
+	if err != nil {
+		return fmt.Errorf("keyspace replication column: %w", err)
+	}
+
+	ks[name] = keyspace{strategy: stg}
+	return nil
+}
+
+// withKeyspaces returns copy of topology with keyspaces replaced by ks.
+// Replicas are recomputed only if replication of the default keyspace has changed.
+func (t *topology) withKeyspaces(ks ksMap, defaultKs string) *topology {
+	v := *t
+	v.keyspaces = ks
+
+	prev, prevOk := t.keyspaces[defaultKs]
+	cur, curOk := ks[defaultKs]
+	if prevOk == curOk && prev.strategy.equal(cur.strategy) {
+		return &v
+	}
+
+	v.policyInfo, _ = v.policyInfoFor(cur)
+	return &v
+}
+
+// policyInfoFor computes replicas of ks on a copy of the ring, ring entries are shared with
+// the old topology, which may still be in use. Replicas are not copied as strategies set only
+// some of them. It returns false if ks strategy is unknown, the ring has no replicas then.
+func (t *topology) policyInfoFor(ks keyspace) (policyInfo, bool) {
+	pi := policyInfo{ring: make(Ring, len(t.policyInfo.ring))}
+	for i, e := range t.policyInfo.ring {
+		pi.ring[i] = RingEntry{node: e.node, token: e.token}
+	}
+	ok := pi.Preprocess(t, ks)
+	return pi, ok
+}
+
+func (s strategy) equal(o strategy) bool {
+	if s.class != o.class || s.rf != o.rf || len(s.dcRF) != len(o.dcRF) || len(s.data) != len(o.data) {
+		return false
+	}
+	for k, v := range s.dcRF {
+		if w, ok := o.dcRF[k]; !ok || v != w {
+			return false
+		}
+	}
+	for k, v := range s.data {
+		if w, ok := o.data[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func withTextValues(s Statement, values ...string) Statement {
+	s.Values = make([]frame.Value, len(values))
+	for i, v := range values {
+		s.Values[i] = frame.Value{
+			N:     frame.Int(len(v)),
+			Bytes: []byte(v),
+		}
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package transport
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/atomic"
+)
+
+func TestTopologyWithKeyspaces(t *testing.T) {
+	t.Parallel()
+
+	top := mockTopologyTokenAwareSimpleStrategy()
+	c := mockCluster(top, "rf2", "")
+	old := c.Topology()
+
+	testCases := []struct {
+		name     string
+		strategy strategy
+		replicas int
+	}{
+		{
+			name:     "same replication",
+			strategy: strategy{class: simpleStrategy, rf: 2},
+			replicas: 2,
+		},
+		{
+			name:     "changed replication",
+			strategy: strategy{class: simpleStrategy, rf: 3},
+			replicas: 3,
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ks := ksMap{"rf2": {strategy: tc.strategy}}
+			v := old.withKeyspaces(ks, "rf2")
+			for _, e := range v.policyInfo.ring {
+				if len(e.localReplicas) != tc.replicas {
+					t.Fatalf("got %d replicas, expected %d", len(e.localReplicas), tc.replicas)
+				}
+			}
+			for _, e := range old.policyInfo.ring {
+				if len(e.localReplicas) != 2 {
+					t.Fatalf("old topology was modified, got %d replicas", len(e.localReplicas))
+				}
+			}
+		})
+	}
+}
+
+func TestTopologyWithKeyspacesStrategyChange(t *testing.T) {
+	t.Parallel()
+
+	top := mockTopologyTokenAwareDCAwareStrategy()
+	c := mockCluster(top, "waw/her", "waw")
+	old := c.Topology()
+
+	testCases := []struct {
+		name   string
+		stg    strategy
+		local  int
+		remote int
+	}{
+		{
+			name:  "network topology to simple strategy",
+			stg:   strategy{class: simpleStrategy, rf: 1},
+			local: 1,
+		},
+		{
+			name: "network topology to unknown strategy",
+			stg:  strategy{class: "com.example.CustomStrategy"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := old.withKeyspaces(ksMap{"waw/her": {strategy: tc.stg}}, "waw/her")
+			for _, e := range v.policyInfo.ring {
+				if len(e.localReplicas) != tc.local || len(e.remoteReplicas) != tc.remote {
+					t.Fatalf("got %d local and %d remote replicas, expected %d and %d",
+						len(e.localReplicas), len(e.remoteReplicas), tc.local, tc.remote)
+				}
+			}
+		})
+	}
+}
+
+func TestSchemaChangesDebounce(t *testing.T) {
+	t.Parallel()
+
+	var (
+		s     schemaChanges
+		calls atomic.Int32
+		done  = make(chan struct{}, 10)
+	)
+	f := func() {
+		calls.Inc()
+		done <- struct{}{}
+	}
+
+	for i := 0; i < 5; i++ {
+		s.add([]schemaChange{{keyspace: "ks1"}}, nil, f)
+		s.add([]schemaChange{{keyspace: "ks2"}}, nil, f)
+	}
+
+	<-done
+	time.Sleep(2 * schemaRefreshDelay)
+	if v := calls.Load(); v != 1 {
+		t.Fatalf("expected 1 refresh request, got %d", v)
+	}
+	if v, _ := s.take(); len(v) != 2 {
+		t.Fatalf("expected 2 pending changes, got %+v", v)
+	}
+	if v, _ := s.take(); len(v) != 0 {
+		t.Fatalf("expected no pending changes, got %+v", v)
+	}
+}
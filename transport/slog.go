@@ -0,0 +1,16 @@
+//go:build go1.21
+
+package transport
+
+import (
+	"log/slog"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+type SlogLogger = frame.SlogLogger
+
+// NewSlogLogger returns Logger writing to l, if l is nil slog.Default is used.
+func NewSlogLogger(l *slog.Logger) SlogLogger {
+	return frame.NewSlogLogger(l)
+}
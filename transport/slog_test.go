@@ -0,0 +1,28 @@
+//go:build go1.21
+
+package transport
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Debug("hidden")
+	l.Warn("missed heartbeat", "conn", "[addr=10.0.0.1:9042 shard=1]")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Fatalf("debug message logged: %s", out)
+	}
+	if !strings.Contains(out, `level=WARN msg="missed heartbeat" conn="[addr=10.0.0.1:9042 shard=1]"`) {
+		t.Fatalf("got %s", out)
+	}
+}
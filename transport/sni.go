@@ -0,0 +1,49 @@
+package transport
+
+import (
+	"crypto/tls"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+)
+
+// SNIProxyConfig routes all connections through a TLS proxy which picks the node
+// based on TLS server name, as in Scylla Cloud.
+type SNIProxyConfig struct {
+	// Addr is host:port of the proxy.
+	Addr string
+	// NodeDomain is appended to node host ID to form server name of connections to the node,
+	// connections with NodeDomain as server name are routed to any node.
+	NodeDomain string
+	// Datacenters maps datacenter name to the proxy of its nodes, nodes of other datacenters
+	// and the control connection use Addr and NodeDomain.
+	Datacenters map[string]SNIProxyDatacenter
+}
+
+// SNIProxyDatacenter is a proxy of nodes in a single datacenter.
+type SNIProxyDatacenter struct {
+	Addr       string
+	NodeDomain string
+	// TLSConfig is used for connections to the datacenter, if it's nil ConnConfig.TLSConfig is used.
+	TLSConfig *tls.Config
+}
+
+// forNode returns config of connections to node with given host ID in datacenter dc.
+func (cfg ConnConfig) forNode(hostID frame.UUID, dc string) ConnConfig {
+	if cfg.SNIProxy == nil || cfg.TLSConfig == nil {
+		return cfg
+	}
+	v := cfg
+	tlsConfig := cfg.TLSConfig
+	if d, ok := cfg.SNIProxy.Datacenters[dc]; ok {
+		v.SNIProxy = &SNIProxyConfig{
+			Addr:       d.Addr,
+			NodeDomain: d.NodeDomain,
+		}
+		if d.TLSConfig != nil {
+			tlsConfig = d.TLSConfig
+		}
+	}
+	v.TLSConfig = tlsConfig.Clone()
+	v.TLSConfig.ServerName = formatUUID(hostID) + "." + v.SNIProxy.NodeDomain
+	return v
+}
@@ -0,0 +1,74 @@
+package transport
+
+import (
+	"errors"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+)
+
+// MaxStreams is the number of stream IDs available on a connection.
+const MaxStreams = maxStreamID
+
+// ConnStats is a snapshot of connection request counters.
+type ConnStats struct {
+	Shard int
+	// InFlight is the number of requests sent and waiting for response, each uses a stream ID.
+	InFlight int
+	// InQueue is the number of requests waiting to be sent.
+	InQueue int
+}
+
+func (c *Conn) Stats() ConnStats {
+	return ConnStats{
+		Shard:    c.Shard(),
+		InFlight: int(c.stats.inFlight.Load()),
+		InQueue:  int(c.stats.inQueue.Load()),
+	}
+}
+
+// HostStats is a snapshot of request counters of node connections.
+type HostStats struct {
+	Host  HostInfo
+	Conns []ConnStats
+}
+
+// Stats returns stats of all nodes connections.
+func (c *Cluster) Stats() []HostStats {
+	nodes := c.Topology().nodes
+	res := make([]HostStats, len(nodes))
+	for i, n := range nodes {
+		res[i] = HostStats{
+			Host:  n.Info(),
+			Conns: n.connStats(),
+		}
+	}
+	return res
+}
+
+func (n *Node) connStats() []ConnStats {
+	if n.pool == nil {
+		return nil
+	}
+	var res []ConnStats
+	for i := range n.pool.conns {
+		if conn := n.pool.loadConn(i); conn != nil {
+			res = append(res, conn.Stats())
+		}
+	}
+	return res
+}
+
+// IsTimeout returns true if err is a read or write timeout reported by node.
+func IsTimeout(err error) bool {
+	var v CodedError
+	if !errors.As(err, &v) {
+		return false
+	}
+	switch v.ErrorCode() {
+	case frame.ErrCodeReadTimeout, frame.ErrCodeWriteTimeout:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,9 +1,12 @@
 package transport
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/mmatczuk/scylla-go-driver/frame"
+	"go.uber.org/atomic"
 )
 
 func TestStreamIDAllocator(t *testing.T) {
@@ -47,3 +50,97 @@ func TestStreamIDAllocator(t *testing.T) {
 		}
 	}
 }
+
+func newTestConnReader(maxWaiters int, timeout time.Duration) *connReader {
+	c := &connReader{
+		h:                 make(map[frame.StreamID]ResponseHandler),
+		connString:        func() string { return "[test]" },
+		streamFreed:       make(chan struct{}, maxWaiters),
+		maxStreamWaiters:  maxWaiters,
+		streamWaitTimeout: timeout,
+	}
+	for i := 0; i <= maxStreamID; i++ {
+		if _, err := c.setHandler(MakeResponseHandler()); err != nil {
+			panic(err)
+		}
+	}
+	return c
+}
+
+func TestConnReaderStreamWaitQueue(t *testing.T) {
+	t.Parallel()
+
+	var rejected atomic.Int32
+	c := newTestConnReader(1, time.Second)
+	c.onExhausted = func(waiting int, r bool) {
+		if r {
+			rejected.Inc()
+		}
+	}
+
+	done := make(chan frame.StreamID)
+	go func() {
+		streamID, err := c.setHandler(MakeResponseHandler())
+		if err != nil {
+			t.Error(err)
+		}
+		done <- streamID
+	}()
+
+	// Wait until the request is queued, queue is full, so the next request should be rejected.
+	for {
+		c.mu.Lock()
+		waiters := c.streamWaiters
+		c.mu.Unlock()
+		if waiters == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := c.setHandler(MakeResponseHandler()); !errors.Is(err, errAllStreamsBusy) {
+		t.Fatalf("expected %s, got %v", errAllStreamsBusy, err)
+	}
+	if rejected.Load() != 1 {
+		t.Fatalf("expected rejection to be reported")
+	}
+
+	c.handler(42)
+	if streamID := <-done; streamID != 42 {
+		t.Fatalf("expected freed stream 42, got %d", streamID)
+	}
+}
+
+func TestConnReaderStreamWaitTimeout(t *testing.T) {
+	t.Parallel()
+
+	c := newTestConnReader(1, 10*time.Millisecond)
+	if _, err := c.setHandler(MakeResponseHandler()); !errors.Is(err, errAllStreamsBusy) {
+		t.Fatalf("expected %s, got %v", errAllStreamsBusy, err)
+	}
+}
+
+func TestConnReaderStreamWaitClose(t *testing.T) {
+	t.Parallel()
+
+	c := newTestConnReader(1, time.Minute)
+	done := make(chan error)
+	go func() {
+		_, err := c.setHandler(MakeResponseHandler())
+		done <- err
+	}()
+
+	for {
+		c.mu.Lock()
+		waiters := c.streamWaiters
+		c.mu.Unlock()
+		if waiters == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c.drainHandlers()
+	if err := <-done; err == nil || errors.Is(err, errAllStreamsBusy) {
+		t.Fatalf("expected closed error, got %v", err)
+	}
+}
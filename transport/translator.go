@@ -0,0 +1,46 @@
+package transport
+
+import (
+	"net"
+)
+
+// AddressTranslator translates addresses of nodes discovered in system tables to addresses
+// the driver connects to, it's needed when nodes are behind NAT or Kubernetes services.
+// Addresses are in host:port format, translator is called for the CQL port and for
+// the shard aware port of every node.
+type AddressTranslator interface {
+	Translate(addr string) string
+}
+
+// StaticAddressTranslator translates addresses using a map, keys are either host:port
+// or host only, in which case the port is kept. Addresses not present in the map are not translated.
+type StaticAddressTranslator map[string]string
+
+var _ AddressTranslator = StaticAddressTranslator(nil)
+
+func (m StaticAddressTranslator) Translate(addr string) string {
+	if v, ok := m[addr]; ok {
+		return v
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	if v, ok := m[host]; ok {
+		return withPort(v, port)
+	}
+	return addr
+}
+
+// translate returns addr translated with AddressTranslator if it's set.
+func (cfg ConnConfig) translate(addr string) string {
+	if cfg.AddressTranslator == nil {
+		return addr
+	}
+	return cfg.AddressTranslator.Translate(addr)
+}
+
+// nodeAddr returns address used to connect to the node with given IP.
+func (cfg ConnConfig) nodeAddr(host string) string {
+	return cfg.translate(withPort(host, cfg.DefaultPort))
+}
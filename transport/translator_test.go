@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/mmatczuk/scylla-go-driver/frame"
+	. "github.com/mmatczuk/scylla-go-driver/frame/response"
+	"github.com/mmatczuk/scylla-go-driver/internal/testutil"
+)
+
+func TestStaticAddressTranslator(t *testing.T) {
+	t.Parallel()
+
+	tr := StaticAddressTranslator{
+		"1":          "node1.example.com",
+		"2:9042":     "node2.example.com:30042",
+		"2:19042":    "node2.example.com:31042",
+		"5":          "10.0.0.5",
+		"[::1]:9042": "localhost:9042",
+		"3":          "[2001:db8::3]",
+	}
+	cfg := DefaultConnConfig("")
+	cfg.AddressTranslator = tr
+
+	top := mockTopologyTokenAwareDCAwareStrategy()
+	expected := map[string][2]string{
+		"1": {"node1.example.com:9042", "node1.example.com:19042"},
+		"2": {"node2.example.com:30042", "node2.example.com:31042"},
+		"3": {"[2001:db8::3]:9042", "[2001:db8::3]:19042"},
+		"4": {"4:9042", "4:19042"},
+		"5": {"10.0.0.5:9042", "10.0.0.5:19042"},
+	}
+
+	for _, n := range top.nodes {
+		e, ok := expected[n.addr]
+		if !ok {
+			e = [2]string{n.addr + ":9042", n.addr + ":19042"}
+		}
+		if v := cfg.nodeAddr(n.addr); v != e[0] {
+			t.Fatalf("node %s: got %s, expected %s", n.addr, v, e[0])
+		}
+		if v := cfg.translate(n.addr + ":19042"); v != e[1] {
+			t.Fatalf("node %s shard aware port: got %s, expected %s", n.addr, v, e[1])
+		}
+	}
+
+	if v := cfg.nodeAddr("::1"); v != "localhost:9042" {
+		t.Fatalf("got %s, expected localhost:9042", v)
+	}
+
+	cfg.AddressTranslator = nil
+	if v := cfg.nodeAddr("1"); v != "1:9042" {
+		t.Fatalf("got %s, expected no translation", v)
+	}
+}
+
+// recordingDialer records dialed addresses and connects to a fake node with two shards and shard aware port 19042.
+type recordingDialer struct {
+	addrs chan string
+}
+
+var _ LocalAddrDialer = recordingDialer{}
+
+func (d recordingDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	select {
+	case d.addrs <- addr:
+	default:
+	}
+	return testutil.NewConn(func(h frame.Header, body []byte) (frame.OpCode, []byte) {
+		switch h.OpCode {
+		case frame.OpOptions:
+			var b frame.Buffer
+			b.WriteStringMultiMap(frame.StringMultiMap{
+				ScyllaShard:             {"0"},
+				ScyllaNrShards:          {"2"},
+				ScyllaPartitioner:       {"org.apache.cassandra.dht.Murmur3Partitioner"},
+				ScyllaShardingAlgorithm: {"biased-token-round-robin"},
+				ScyllaShardingIgnoreMSB: {"12"},
+				ScyllaShardAwarePort:    {"19042"},
+			})
+			return frame.OpSupported, b.Bytes()
+		case frame.OpStartup:
+			return frame.OpReady, nil
+		default:
+			return 0, nil
+		}
+	}), nil
+}
+
+func (d recordingDialer) DialContextFrom(ctx context.Context, network string, localAddr *net.TCPAddr, addr string) (net.Conn, error) {
+	return d.DialContext(ctx, network, addr)
+}
+
+func TestConnPoolDialsTranslatedAddress(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		dialer   func(d recordingDialer) Dialer
+		expected []string
+	}{
+		{
+			name:     "shard aware",
+			dialer:   func(d recordingDialer) Dialer { return d },
+			expected: []string{"node1.example.com:9042", "node1.example.com:19042"},
+		},
+		{
+			name:     "shard unaware",
+			dialer:   func(d recordingDialer) Dialer { return DialerFunc(d.DialContext) },
+			expected: []string{"node1.example.com:9042", "node1.example.com:9042"},
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			d := recordingDialer{addrs: make(chan string, 10)}
+			cfg := DefaultConnConfig("")
+			cfg.Dialer = tc.dialer(d)
+			cfg.AddressTranslator = StaticAddressTranslator{"10.0.0.1": "node1.example.com"}
+
+			p, err := NewConnPool("10.0.0.1", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				p.Close()
+				p.Wait()
+			}()
+
+			for _, e := range tc.expected {
+				if v := <-d.addrs; v != e {
+					t.Fatalf("got dial to %s, expected %s", v, e)
+				}
+			}
+		})
+	}
+}